@@ -0,0 +1,102 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// objectGrants are the explicit grant headers applied to uploaded objects
+type objectGrants struct {
+	read        string
+	readACP     string
+	writeACP    string
+	fullControl string
+}
+
+func (g objectGrants) empty() bool {
+	return g == objectGrants{}
+}
+
+func (g objectGrants) apply(input *s3.PutObjectInput) {
+	if g.read != "" {
+		input.GrantRead = aws.String(g.read)
+	}
+	if g.readACP != "" {
+		input.GrantReadACP = aws.String(g.readACP)
+	}
+	if g.writeACP != "" {
+		input.GrantWriteACP = aws.String(g.writeACP)
+	}
+	if g.fullControl != "" {
+		input.GrantFullControl = aws.String(g.fullControl)
+	}
+}
+
+// grants are the object grants set in the config
+func (fc S3FileConfig) grants() objectGrants {
+	return objectGrants{
+		read:        fc.GrantRead,
+		readACP:     fc.GrantReadACP,
+		writeACP:    fc.GrantWriteACP,
+		fullControl: fc.GrantFullControl,
+	}
+}
+
+// setsACL reports whether uploads may carry an acl or grants, from the config, the bucket acl or the rules
+func (fc S3FileConfig) setsACL() bool {
+	if fc.ACL != "" || !fc.grants().empty() || fc.InheritBucketACL {
+		return true
+	}
+
+	for _, rule := range fc.Rules {
+		if rule.ACL != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bucketGrants reads the ACL of the bucket and turns it into the equivalent object grants.
+// WRITE has no meaning on objects, so it is left out.
+func bucketGrants(ctx context.Context, client s3API, bucket string) (objectGrants, error) {
+	out, err := client.GetBucketAcl(ctx, &s3.GetBucketAclInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return objectGrants{}, fmt.Errorf("reading acl of bucket %s: %w", bucket, err)
+	}
+
+	grantees := map[types.Permission][]string{}
+	for _, grant := range out.Grants {
+		if grant.Grantee == nil {
+			continue
+		}
+
+		var grantee string
+		switch {
+		case grant.Grantee.ID != nil:
+			grantee = fmt.Sprintf("id=%q", aws.ToString(grant.Grantee.ID))
+		case grant.Grantee.URI != nil:
+			grantee = fmt.Sprintf("uri=%q", aws.ToString(grant.Grantee.URI))
+		case grant.Grantee.EmailAddress != nil:
+			grantee = fmt.Sprintf("emailAddress=%q", aws.ToString(grant.Grantee.EmailAddress))
+		default:
+			continue
+		}
+
+		grantees[grant.Permission] = append(grantees[grant.Permission], grantee)
+	}
+
+	return objectGrants{
+		read:        strings.Join(grantees[types.PermissionRead], ", "),
+		readACP:     strings.Join(grantees[types.PermissionReadAcp], ", "),
+		writeACP:    strings.Join(grantees[types.PermissionWriteAcp], ", "),
+		fullControl: strings.Join(grantees[types.PermissionFullControl], ", "),
+	}, nil
+}
@@ -0,0 +1,49 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestInheritBucketACL(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	fake.bucketACL = []types.Grant{
+		{Grantee: &types.Grantee{ID: aws.String("owner")}, Permission: types.PermissionFullControl},
+		{Grantee: &types.Grantee{URI: aws.String("http://acs.amazonaws.com/groups/global/AllUsers")}, Permission: types.PermissionRead},
+		{Grantee: &types.Grantee{URI: aws.String("http://acs.amazonaws.com/groups/s3/LogDelivery")}, Permission: types.PermissionWrite},
+	}
+
+	fc := testConfig("site")
+	fc.InheritBucketACL = true
+	target := testTarget(t, fc, map[string]string{"a.txt": "a"})
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	input := fake.putInput("site/a.txt")
+	if got := aws.ToString(input.GrantFullControl); got != `id="owner"` {
+		t.Errorf("got full control grant %q", got)
+	}
+	if got := aws.ToString(input.GrantRead); got != `uri="http://acs.amazonaws.com/groups/global/AllUsers"` {
+		t.Errorf("got read grant %q", got)
+	}
+	if input.GrantWriteACP != nil || input.GrantReadACP != nil || input.ACL != "" {
+		t.Errorf("got other grants or an acl: %v, %v, %q", input.GrantWriteACP, input.GrantReadACP, input.ACL)
+	}
+
+	// a canned acl takes precedence over the one of the bucket
+	fc.ACL = string(types.ObjectCannedACLPrivate)
+	fake.calls = nil
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if n := fake.count("GetBucketAcl"); n != 0 {
+		t.Errorf("read the bucket acl %d times despite the canned acl", n)
+	}
+	if input := fake.putInput("site/a.txt"); input.GrantRead != nil || input.ACL != types.ObjectCannedACLPrivate {
+		t.Errorf("got acl %q and read grant %v, want only the canned acl", input.ACL, input.GrantRead)
+	}
+}
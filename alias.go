@@ -0,0 +1,168 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// maxCopySize is the largest object a single CopyObject copies, larger ones are copied in parts of copyPartSize.
+// They are variables so tests do not need objects of several gigabytes.
+var (
+	maxCopySize  int64 = 5 << 30
+	copyPartSize int64 = 512 << 20
+)
+
+// aliasKeys are the keys an object is copied to, one under each alias prefix. The key keeps its path
+// relative to the prefix, e.g. v1.2.3/app.tar.gz is aliased as latest/app.tar.gz.
+func (d *deployment) aliasKeys(key string) []string {
+	keys := make([]string, 0, len(d.fc.Aliases))
+	for _, alias := range d.fc.Aliases {
+		keys = append(keys, applyKeyCase(d.fc.KeyCase, path.Join(enforcePrefix(alias), d.receiptKey(key))))
+	}
+
+	return keys
+}
+
+// copyToAliases copies the object at key to each of its alias keys, server side. Objects that did not need
+// to be uploaded are copied as well, as the aliases may point to another deploy.
+func (d *deployment) copyToAliases(ctx context.Context, key, rel string) error {
+	aliases := d.aliasKeys(key)
+	if d.runCtx.DryRun {
+		for _, alias := range aliases {
+			d.target.Infoln("copy s3://%s/%s to s3://%s/%s", d.bucket, key, d.bucket, alias)
+		}
+		return nil
+	}
+
+	// the size of the object decides how it can be copied
+	head, err := d.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(d.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("reading %q to copy it to its aliases: %w", key, err)
+	}
+
+	for _, alias := range aliases {
+		// the object settings that are not copied along with the object are set again
+		settings, err := d.putObjectInput(alias, rel, nil)
+		if err != nil {
+			return err
+		}
+
+		if head.ContentLength > maxCopySize {
+			err = d.copyInParts(ctx, key, alias, head, settings)
+		} else {
+			_, err = d.client.CopyObject(ctx, &s3.CopyObjectInput{
+				Bucket:                    aws.String(d.bucket),
+				Key:                       aws.String(alias),
+				CopySource:                aws.String(copySource(d.bucket, key)),
+				MetadataDirective:         types.MetadataDirectiveCopy,
+				ACL:                       settings.ACL,
+				GrantRead:                 settings.GrantRead,
+				GrantReadACP:              settings.GrantReadACP,
+				GrantWriteACP:             settings.GrantWriteACP,
+				GrantFullControl:          settings.GrantFullControl,
+				ServerSideEncryption:      settings.ServerSideEncryption,
+				SSEKMSKeyId:               settings.SSEKMSKeyId,
+				BucketKeyEnabled:          settings.BucketKeyEnabled,
+				StorageClass:              settings.StorageClass,
+				ObjectLockMode:            settings.ObjectLockMode,
+				ObjectLockRetainUntilDate: settings.ObjectLockRetainUntilDate,
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("copying %q to alias %q: %w", key, alias, err)
+		}
+
+		d.debugln(alias, "copied from %s", key)
+	}
+
+	return nil
+}
+
+// copyInParts copies an object too large for CopyObject with a multipart upload of UploadPartCopy ranges.
+// Unlike CopyObject, a multipart upload does not carry over the headers and metadata, so they are taken from head.
+func (d *deployment) copyInParts(ctx context.Context, key, alias string, head *s3.HeadObjectOutput, settings *s3.PutObjectInput) error {
+	upload, err := d.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:                    aws.String(d.bucket),
+		Key:                       aws.String(alias),
+		ContentType:               head.ContentType,
+		ContentEncoding:           head.ContentEncoding,
+		ContentDisposition:        head.ContentDisposition,
+		ContentLanguage:           head.ContentLanguage,
+		CacheControl:              head.CacheControl,
+		Expires:                   head.Expires,
+		WebsiteRedirectLocation:   head.WebsiteRedirectLocation,
+		Metadata:                  head.Metadata,
+		ACL:                       settings.ACL,
+		GrantRead:                 settings.GrantRead,
+		GrantReadACP:              settings.GrantReadACP,
+		GrantWriteACP:             settings.GrantWriteACP,
+		GrantFullControl:          settings.GrantFullControl,
+		ServerSideEncryption:      settings.ServerSideEncryption,
+		SSEKMSKeyId:               settings.SSEKMSKeyId,
+		BucketKeyEnabled:          settings.BucketKeyEnabled,
+		StorageClass:              settings.StorageClass,
+		ObjectLockMode:            settings.ObjectLockMode,
+		ObjectLockRetainUntilDate: settings.ObjectLockRetainUntilDate,
+	})
+	if err != nil {
+		return err
+	}
+
+	size := head.ContentLength
+	partSize := uploadPartSize(size, copyPartSize)
+
+	parts := []types.CompletedPart{}
+	for number, start := int32(1), int64(0); start < size; number, start = number+1, start+partSize {
+		end := start + partSize
+		if end > size {
+			end = size
+		}
+
+		out, err := d.client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:          aws.String(d.bucket),
+			Key:             aws.String(alias),
+			UploadId:        upload.UploadId,
+			PartNumber:      number,
+			CopySource:      aws.String(copySource(d.bucket, key)),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", start, end-1)),
+			// every part has to come from the same object
+			CopySourceIfMatch: head.ETag,
+		})
+		if err != nil {
+			d.abortCopy(alias, upload.UploadId)
+			return fmt.Errorf("copying part %d: %w", number, err)
+		}
+		parts = append(parts, types.CompletedPart{ETag: out.CopyPartResult.ETag, PartNumber: number})
+	}
+
+	if _, err := d.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(d.bucket),
+		Key:             aws.String(alias),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	}); err != nil {
+		d.abortCopy(alias, upload.UploadId)
+		return err
+	}
+
+	return nil
+}
+
+// abortCopy cleans up the parts of a failed multipart copy, with a context that still works once the deploy was cancelled
+func (d *deployment) abortCopy(alias string, uploadID *string) {
+	if _, err := d.client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(d.bucket),
+		Key:      aws.String(alias),
+		UploadId: uploadID,
+	}); err != nil {
+		d.debugln(alias, "aborting multipart copy: %v", err)
+	}
+}
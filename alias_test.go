@@ -0,0 +1,42 @@
+package s3
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAliasesOfLargeObjectsAreCopiedInParts(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	origMax, origPart := maxCopySize, copyPartSize
+	maxCopySize, copyPartSize = 10, 4
+	t.Cleanup(func() { maxCopySize, copyPartSize = origMax, origPart })
+
+	large := strings.Repeat("0123456789", 2) + "01234"
+	fc := testConfig("site")
+	fc.Aliases = []string{"latest"}
+	target := testTarget(t, fc, map[string]string{"large.txt": large, "small.txt": "small"})
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	if got := string(fake.object("latest/large.txt").body); got != large {
+		t.Errorf("latest/large.txt holds %q, want %q", got, large)
+	}
+	if got := fake.object("latest/large.txt").contentType; got != "text/plain; charset=utf-8" {
+		t.Errorf("latest/large.txt has content type %q", got)
+	}
+	if got := string(fake.object("latest/small.txt").body); got != "small" {
+		t.Errorf("latest/small.txt holds %q", got)
+	}
+
+	// 25 bytes in parts of 4
+	if n := fake.count("UploadPartCopy"); n != 7 {
+		t.Errorf("copied %d parts, want 7", n)
+	}
+	if got := fake.keys("CopyObject"); len(got) != 1 || got[0] != "latest/small.txt" {
+		t.Errorf("copied %v with CopyObject, want only the small object", got)
+	}
+}
@@ -0,0 +1,135 @@
+package s3
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	archiveTarGz = "tar.gz"
+	archiveZip   = "zip"
+)
+
+// archiveContentTypes are the content types of the archives, which are not always known to the mime package
+var archiveContentTypes = map[string]string{
+	archiveTarGz: "application/gzip",
+	archiveZip:   "application/zip",
+}
+
+// uploadArchive packs outs into a single archive, with the paths relative to the target cwd, and uploads it to the archive key
+func (d *deployment) uploadArchive(ctx context.Context, outs []string) (string, error) {
+	archive, err := d.fc.writeArchive(d.target.Cwd, outs)
+	if err != nil {
+		return "", fmt.Errorf("failed to archive outs: %w", err)
+	}
+	defer removeTempFile(archive)
+
+	info, err := archive.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat archive: %w", err)
+	}
+
+	return d.send(ctx, d.contentKey(d.fc.ArchiveKey), d.fc.ArchiveKey, archive, info.Size(), time.Time{}, nil)
+}
+
+// writeArchive packs outs into a temporary file in the temp dir, positioned at its start.
+// The caller is responsible for removing it with removeTempFile.
+func (fc S3FileConfig) writeArchive(cwd string, outs []string) (*os.File, error) {
+	tmp, err := os.CreateTemp(fc.TempDir, "zen-s3-*."+fc.Archive)
+	if err != nil {
+		return nil, err
+	}
+
+	if fc.Archive == archiveZip {
+		err = writeZip(tmp, cwd, outs)
+	} else {
+		err = writeTarGz(tmp, cwd, outs)
+	}
+	if err != nil {
+		removeTempFile(tmp)
+		return nil, err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		removeTempFile(tmp)
+		return nil, err
+	}
+
+	return tmp, nil
+}
+
+func writeTarGz(w io.Writer, cwd string, outs []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, out := range outs {
+		info, err := os.Stat(out)
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = relPath(cwd, out)
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if err := copyFileTo(tw, out); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeZip(w io.Writer, cwd string, outs []string) error {
+	zw := zip.NewWriter(w)
+
+	for _, out := range outs {
+		info, err := os.Stat(out)
+		if err != nil {
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = relPath(cwd, out)
+		header.Method = zip.Deflate
+
+		entry, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if err := copyFileTo(entry, out); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// copyFileTo writes the content of the file at f to w, following symlinks
+func copyFileTo(w io.Writer, f string) error {
+	file, err := os.Open(f)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+	return err
+}
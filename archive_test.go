@@ -0,0 +1,92 @@
+package s3
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArchiveContentTypeCanBeOverriddenByRules(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		rules []S3ObjectRule
+		want  string
+	}{
+		{name: "format", want: "application/gzip"},
+		{name: "rule", rules: []S3ObjectRule{{Match: "*.tar.gz", ContentType: "application/x-gtar"}}, want: "application/x-gtar"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3()
+			useFake(t, fake)
+
+			fc := testConfig("site")
+			fc.Archive = archiveTarGz
+			fc.Rules = tt.rules
+			target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+
+			if err := runScript(t, fc, "deploy", target, nil); err != nil {
+				t.Fatalf("deploy: %v", err)
+			}
+
+			obj := fake.object("site/site.tar.gz")
+			if obj == nil {
+				t.Fatalf("archive not uploaded, stored %v", fake.stored())
+			} else if obj.contentType != tt.want {
+				t.Errorf("archive has content type %q, want %q", obj.contentType, tt.want)
+			}
+		})
+	}
+}
+
+func TestArchiveKeyIsRemovedAndPresigned(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	// without a prefix, the keys are derived from the outs instead of listed
+	fc := testConfig("")
+	fc.Archive = archiveZip
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>", "app.js": "run()"})
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if got := fake.stored(); !reflect.DeepEqual(got, []string{"site.zip"}) {
+		t.Fatalf("stored %v, want only the archive", got)
+	}
+
+	if err := runScript(t, fc, "presign", target, nil); err != nil {
+		t.Fatalf("presign: %v", err)
+	}
+	if !logged(target, "site.zip\t") || logged(target, "index.html\t") {
+		t.Errorf("presigned other keys than the archive: %v", target.Logs)
+	}
+
+	if err := runScript(t, fc, "remove", target, nil); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if got := fake.stored(); len(got) != 0 {
+		t.Errorf("left %v after remove", got)
+	}
+	if got := fake.keys("DeleteObjects"); len(got) != 1 {
+		t.Errorf("made %d DeleteObjects calls, want 1", len(got))
+	}
+}
+
+func TestArchiveJobCountsTheSizeOfTheOuts(t *testing.T) {
+	fc := testConfig("site")
+	fc.Archive = archiveTarGz
+	fc.ArchiveKey = "site.tar.gz"
+	target := testTarget(t, fc, map[string]string{"a.txt": "12345", "b.txt": "123"})
+
+	d := &deployment{fc: fc, target: target, prefix: "site"}
+	jobs := []uploadJob{}
+	d.eachUploadJob(target.Outs, func(job uploadJob) bool {
+		jobs = append(jobs, job)
+		return true
+	})
+	if len(jobs) != 1 {
+		t.Fatalf("got %d jobs, want the archive only", len(jobs))
+	}
+	if jobs[0].size != 8 {
+		t.Errorf("archive job has size %d, want the 8 bytes of the outs", jobs[0].size)
+	}
+}
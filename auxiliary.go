@@ -0,0 +1,33 @@
+package s3
+
+import (
+	"context"
+	"sync"
+)
+
+// maxAuxiliaryUploads bounds how many of the objects describing a deploy, like the sitemap, the manifest
+// and the receipts, are uploaded at once. There are only a few of them, and they are small.
+const maxAuxiliaryUploads = 4
+
+// uploadAuxiliary runs uploads that do not depend on each other concurrently, at most maxAuxiliaryUploads
+// at a time, and returns the errors of all of them
+func uploadAuxiliary(ctx context.Context, uploads ...func(ctx context.Context) error) error {
+	sem := make(chan struct{}, maxAuxiliaryUploads)
+	var wg sync.WaitGroup
+	var errs multiError
+
+	for _, upload := range uploads {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(upload func(ctx context.Context) error) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			errs.add(upload(ctx))
+		}(upload)
+	}
+
+	wg.Wait()
+	return errs.err()
+}
@@ -0,0 +1,84 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAuxiliaryUploadsRunConcurrently(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	// the sitemap and the manifest each wait for the other one to start
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	fake.hook = func(ctx context.Context, op, key string) error {
+		if op != "PutObject" || !(strings.HasSuffix(key, sitemapName) || strings.HasSuffix(key, manifestName)) {
+			return nil
+		}
+		started <- key
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+		return nil
+	}
+
+	fc := testConfig("site")
+	fc.SitemapBaseURL = "https://example.com"
+	fc.RecordManifest = true
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+
+	done := make(chan error)
+	go func() { done <- runScript(t, fc, "deploy", target, nil) }()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the sitemap and the manifest were not uploaded concurrently")
+		}
+	}
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if fake.object("site/"+sitemapName) == nil || fake.object("site/"+manifestName) == nil {
+		t.Errorf("missing auxiliary objects, stored %v", fake.stored())
+	}
+}
+
+func TestUploadAuxiliaryIsBounded(t *testing.T) {
+	var running, peak atomic.Int32
+	uploads := []func(ctx context.Context) error{}
+	for i := 0; i < 3*maxAuxiliaryUploads; i++ {
+		uploads = append(uploads, func(ctx context.Context) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})
+	}
+	failing := errors.New("failed")
+	uploads = append(uploads, func(ctx context.Context) error { return failing })
+
+	if err := uploadAuxiliary(context.Background(), uploads...); !errors.Is(err, failing) {
+		t.Errorf("got error %v, want %v", err, failing)
+	}
+	if p := peak.Load(); p > maxAuxiliaryUploads {
+		t.Errorf("ran %d uploads at once, above the limit of %d", p, maxAuxiliaryUploads)
+	} else if p < 2 {
+		t.Errorf("ran %d upload at a time", p)
+	}
+}
@@ -0,0 +1,218 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// newAwsConfig loads the aws configuration shared by every client the target creates.
+// The region and endpoint of the destination, when set, take precedence over the ones of the target.
+// See resolveRegion for how the region is picked.
+func newAwsConfig(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext, fc S3FileConfig, dest S3Destination) (aws.Config, error) {
+	endpoint, err := resolveEndpoint(target, runCtx, fc, dest)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+		// an overridden endpoint applies to whatever region is configured
+		if service == s3.ServiceID && endpoint != "" {
+			return aws.Endpoint{
+				PartitionID:   "aws",
+				URL:           endpoint,
+				SigningRegion: region,
+			}, nil
+		}
+		// returning EndpointNotFoundError will allow the service to fallback to it's default resolution
+		return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+	})
+	httpClient, err := fc.newHTTPClient(target, runCtx)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	opts := []func(*config.LoadOptions) error{
+		config.WithEndpointResolverWithOptions(customResolver),
+		config.WithHTTPClient(httpClient),
+	}
+	if region := resolveRegion(target, fc, dest, endpoint); region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	if fc.HonorRetryAfter {
+		opts = append(opts, config.WithRetryer(newRetryAfterRetryer()))
+	}
+	if fc.MaxRequestsPerSecond > 0 {
+		opts = append(opts, config.WithAPIOptions([]func(*middleware.Stack) error{rateLimit(newRateLimiter(fc.MaxRequestsPerSecond))}))
+	}
+
+	if fc.Anonymous {
+		// anonymous credentials skip signing, and stop the default chain from failing when it finds nothing
+		opts = append(opts, config.WithCredentialsProvider(aws.AnonymousCredentials{}))
+	} else if fc.AccessKeyID != "" {
+		accessKeyID, err := interpolateAtRuntime(target, runCtx, fc.AccessKeyID)
+		if err != nil {
+			return aws.Config{}, fmt.Errorf("interpolating access key id: %w", err)
+		}
+		secretAccessKey, err := interpolateAtRuntime(target, runCtx, fc.SecretAccessKey)
+		if err != nil {
+			return aws.Config{}, fmt.Errorf("interpolating secret access key: %w", err)
+		}
+
+		// static credentials take precedence over the default provider chain
+		opts = append(opts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, "")))
+	} else {
+		// only the credentials the target declares are used, never those of the process env
+		opts = append(opts, envCredentials(target.Env)...)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.TODO(), opts...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	if !fc.Anonymous {
+		if err := checkCredentials(context.TODO(), cfg); err != nil {
+			return aws.Config{}, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// resolveEndpoint picks the S3 endpoint, from highest to lowest precedence:
+//   - the endpoint of the destination
+//   - the endpoint of the target
+//   - the AWS_S3_ENDPOINT variable, from the target env first and the process env otherwise
+//
+// An empty endpoint leaves the resolution to the SDK defaults.
+func resolveEndpoint(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext, fc S3FileConfig, dest S3Destination) (string, error) {
+	endpoint := fc.Endpoint
+	if dest.Endpoint != "" {
+		endpoint = dest.Endpoint
+	}
+
+	endpoint, err := interpolateAtRuntime(target, runCtx, endpoint)
+	if err != nil {
+		return "", fmt.Errorf("interpolating endpoint: %w", err)
+	}
+	if endpoint != "" {
+		return endpoint, nil
+	}
+
+	if endpoint, ok := target.Env["AWS_S3_ENDPOINT"]; ok {
+		return endpoint, nil
+	}
+
+	return os.Getenv("AWS_S3_ENDPOINT"), nil
+}
+
+// loadAwsConfig loads the aws configuration of the target, along with the bucket and prefix set in its labels
+func loadAwsConfig(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext, fc S3FileConfig) (aws.Config, string, string, error) {
+	cfg, err := newAwsConfig(target, runCtx, fc, S3Destination{})
+	if err != nil {
+		return aws.Config{}, "", "", fmt.Errorf("loading aws config: %w", err)
+	}
+
+	var bucket, prefix, legacyPrefix, tenant string
+	for _, label := range target.Labels {
+		if strings.HasPrefix(label, "zen_bucket=") {
+			interpolated, err := interpolateAtRuntime(target, runCtx, strings.TrimPrefix(label, "zen_bucket="))
+			if err != nil {
+				return aws.Config{}, "", "", fmt.Errorf("interpolating bucket name: %w", err)
+			}
+			bucket = interpolated
+		} else if strings.HasPrefix(label, "zen_bucket_prefix=") {
+			rendered, err := renderPlatform(target, strings.TrimPrefix(label, "zen_bucket_prefix="))
+			if err != nil {
+				return aws.Config{}, "", "", err
+			}
+
+			interpolated, err := interpolateAtRuntime(target, runCtx, rendered)
+			if err != nil {
+				return aws.Config{}, "", "", fmt.Errorf("interpolating bucket key prefix: %w", err)
+			}
+
+			prefix = interpolated
+		} else if strings.HasPrefix(label, "zen_prefix=") {
+			// zen_prefix= is what this used to read, while zen_bucket_prefix= is what GetTargets writes
+			target.Debugln("the zen_prefix= label is deprecated, use zen_bucket_prefix= instead")
+
+			interpolated, err := interpolateAtRuntime(target, runCtx, strings.TrimPrefix(label, "zen_prefix="))
+			if err != nil {
+				return aws.Config{}, "", "", fmt.Errorf("interpolating bucket key prefix: %w", err)
+			}
+
+			legacyPrefix = interpolated
+		} else if strings.HasPrefix(label, "zen_tenant=") {
+			interpolated, err := interpolateAtRuntime(target, runCtx, strings.TrimPrefix(label, "zen_tenant="))
+			if err != nil {
+				return aws.Config{}, "", "", fmt.Errorf("interpolating tenant: %w", err)
+			}
+
+			tenant = interpolated
+		}
+	}
+
+	if prefix == "" {
+		prefix = legacyPrefix
+	}
+	if tenant != "" {
+		prefix = path.Join(prefix, tenant)
+	}
+	if bucket == "" {
+		return aws.Config{}, "", "", fmt.Errorf("bucket is required for s3_file target %q, but it interpolated to an empty value", target.Qn())
+	}
+
+	target.Debugln("Bucket: %s", bucket)
+	target.Debugln("Bucket key: %s", prefix)
+
+	return cfg, bucket, prefix, nil
+}
+
+func newS3Client(cfg aws.Config, fc S3FileConfig) *s3.Client {
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = fc.UsePathStyle == nil || *fc.UsePathStyle
+		o.UseAccelerate = fc.UseAccelerate
+		if fc.RequestPayer {
+			// every request, uploads, deletes and lists alike, has to acknowledge the charges on requester pays buckets
+			o.APIOptions = append(o.APIOptions, smithyhttp.SetHeaderValue("x-amz-request-payer", string(types.RequestPayerRequester)))
+		}
+		if fc.ExpectedBucketOwner != "" {
+			// the same as setting ExpectedBucketOwner on the input of every operation, multipart uploads included
+			o.APIOptions = append(o.APIOptions, smithyhttp.SetHeaderValue("x-amz-expected-bucket-owner", fc.ExpectedBucketOwner))
+		}
+	})
+}
+
+// interpolateAtRuntime interpolates text when a script runs, so on top of the target env it can reference
+// the runtime variables and those of the environment being deployed to, which are not known when parsing the config.
+// The extra vars, e.g. the ones describing the object being uploaded, take precedence over all of them.
+func interpolateAtRuntime(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext, text string, extra ...map[string]string) (string, error) {
+	if runCtx == nil {
+		return target.Interpolate(text, extra...)
+	}
+
+	vars := []map[string]string{runCtx.Variables}
+	if env, ok := runCtx.Environments[runCtx.Env]; ok && env != nil {
+		envVars, err := env.EnvVarsForEnv()
+		if err != nil {
+			return "", fmt.Errorf("loading variables of environment %s: %w", runCtx.Env, err)
+		}
+		vars = append(vars, envVars)
+	}
+
+	return target.Interpolate(text, append(vars, extra...)...)
+}
@@ -0,0 +1,196 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	environs "github.com/zen-io/zen-core/environments"
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+// resolvedEndpoint returns the url the aws config of fc resolves s3 to in region
+func resolvedEndpoint(t *testing.T, fc S3FileConfig, env map[string]string, region string) string {
+	t.Helper()
+
+	target := &zen_targets.Target{Name: fc.Name, Env: env}
+	cfg, err := newAwsConfig(target, &zen_targets.RuntimeContext{}, fc, S3Destination{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint, err := cfg.EndpointResolverWithOptions.ResolveEndpoint(s3.ServiceID, region)
+	if err != nil {
+		t.Fatalf("resolving the endpoint: %v", err)
+	}
+	return endpoint.URL
+}
+
+func TestEndpointPerTarget(t *testing.T) {
+	t.Setenv("AWS_S3_ENDPOINT", "http://process:9000")
+
+	minio := testConfig("site")
+	minio.Endpoint = "http://minio:9000"
+	r2 := testConfig("site")
+	r2.Endpoint = "https://{ACCOUNT}.r2.cloudflarestorage.com"
+
+	if got := resolvedEndpoint(t, minio, map[string]string{}, "us-east-1"); got != "http://minio:9000" {
+		t.Errorf("got %q for the first target", got)
+	}
+	if got := resolvedEndpoint(t, r2, map[string]string{"ACCOUNT": "acme"}, "us-east-1"); got != "https://acme.r2.cloudflarestorage.com" {
+		t.Errorf("got %q for the second target", got)
+	}
+}
+
+func TestBucketResolvedPerEnvironment(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.Bucket = "assets-{STAGE}"
+	target := testTarget(t, fc, map[string]string{"a.txt": "a"})
+	envs := map[string]*environs.Environment{
+		"staging": {Variables: map[string]string{"STAGE": "staging"}},
+		"prod":    {Variables: map[string]string{"STAGE": "prod"}},
+	}
+
+	for _, env := range []string{"staging", "prod"} {
+		if err := runScript(t, fc, "deploy", target, &zen_targets.RuntimeContext{Env: env, Environments: envs}); err != nil {
+			t.Fatalf("deploy to %s: %v", env, err)
+		}
+	}
+
+	buckets := []string{}
+	for _, in := range fake.inputs("PutObject") {
+		buckets = append(buckets, aws.ToString(in.(*s3.PutObjectInput).Bucket))
+	}
+	if want := []string{"assets-staging", "assets-prod"}; !reflect.DeepEqual(buckets, want) {
+		t.Errorf("uploaded to %v, want %v", buckets, want)
+	}
+}
+
+func TestPrefixLabels(t *testing.T) {
+	tests := []struct {
+		name       string
+		labels     []string
+		want       string
+		deprecated bool
+	}{
+		{name: "canonical", labels: []string{"zen_bucket_prefix=site"}, want: "site"},
+		{name: "legacy", labels: []string{"zen_prefix=legacy"}, want: "legacy", deprecated: true},
+		{name: "both", labels: []string{"zen_prefix=legacy", "zen_bucket_prefix=site"}, want: "site", deprecated: true},
+		{name: "none", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := &zen_targets.Target{Name: "site", Env: map[string]string{}, Labels: append([]string{"zen_bucket=bucket"}, tt.labels...)}
+			_, bucket, prefix, err := loadAwsConfig(target, &zen_targets.RuntimeContext{}, testConfig(""))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bucket != "bucket" || prefix != tt.want {
+				t.Errorf("got s3://%s/%s, want s3://bucket/%s", bucket, prefix, tt.want)
+			}
+			if got := logged(target, "deprecated"); got != tt.deprecated {
+				t.Errorf("logged the deprecation: %v, want %v", got, tt.deprecated)
+			}
+		})
+	}
+}
+
+func TestCustomEndpointInAnyRegion(t *testing.T) {
+	fc := testConfig("site")
+	fc.Region = "us-west-2"
+	fc.Endpoint = "https://s3.example.com"
+
+	for _, region := range []string{"us-west-2", "eu-central-1"} {
+		if got := resolvedEndpoint(t, fc, map[string]string{}, region); got != "https://s3.example.com" {
+			t.Errorf("%s resolved to %q", region, got)
+		}
+	}
+
+	// the env var is honored the same way
+	fc.Endpoint = ""
+	if got := resolvedEndpoint(t, fc, map[string]string{"AWS_S3_ENDPOINT": "http://localhost:4566"}, "us-west-2"); got != "http://localhost:4566" {
+		t.Errorf("got %q from AWS_S3_ENDPOINT", got)
+	}
+}
+
+func TestEndpointPrecedence(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		dest, config string
+		targetEnv    map[string]string
+		processEnv   string
+		want         string
+	}{
+		{name: "sdk default", want: ""},
+		{name: "process env", processEnv: "http://process:9000", want: "http://process:9000"},
+		{name: "target env over process env", targetEnv: map[string]string{"AWS_S3_ENDPOINT": "http://target:9000"}, processEnv: "http://process:9000", want: "http://target:9000"},
+		{name: "empty target env restores the sdk default", targetEnv: map[string]string{"AWS_S3_ENDPOINT": ""}, processEnv: "http://process:9000", want: ""},
+		{name: "config over env", config: "http://config:9000", targetEnv: map[string]string{"AWS_S3_ENDPOINT": "http://target:9000"}, processEnv: "http://process:9000", want: "http://config:9000"},
+		{name: "interpolated config", config: "http://{HOST}:9000", targetEnv: map[string]string{"HOST": "minio"}, want: "http://minio:9000"},
+		{name: "destination over config", dest: "http://dest:9000", config: "http://config:9000", processEnv: "http://process:9000", want: "http://dest:9000"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("AWS_S3_ENDPOINT", tc.processEnv)
+
+			fc := testConfig("site")
+			fc.Endpoint = tc.config
+			target := &zen_targets.Target{Name: fc.Name, Env: map[string]string{}}
+			for k, v := range tc.targetEnv {
+				target.Env[k] = v
+			}
+
+			got, err := resolveEndpoint(target, &zen_targets.RuntimeContext{}, fc, S3Destination{Endpoint: tc.dest})
+			if err != nil {
+				t.Fatal(err)
+			} else if got != tc.want {
+				t.Errorf("got endpoint %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// hostRecorder is an http client answering every request with an empty 200, recording the host it was sent to
+type hostRecorder struct {
+	hosts []string
+}
+
+func (h *hostRecorder) Do(r *http.Request) (*http.Response, error) {
+	h.hosts = append(h.hosts, r.URL.Host)
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: r}, nil
+}
+
+func TestAccelerate(t *testing.T) {
+	fc := testConfig("site")
+	fc.UseAccelerate = true
+	fc = fc.withPathStyle("")
+
+	recorder := &hostRecorder{}
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:  recorder,
+	}
+	client := newS3Client(cfg, fc)
+	if _, err := client.PutObject(context.Background(), &s3.PutObjectInput{Bucket: aws.String(testBucket), Key: aws.String("site/a.txt")}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{testBucket + ".s3-accelerate.amazonaws.com"}; !reflect.DeepEqual(recorder.hosts, want) {
+		t.Errorf("sent requests to %v, want %v", recorder.hosts, want)
+	}
+
+	pathStyle := true
+	fc.UsePathStyle = &pathStyle
+	if err := fc.validate(); err == nil || !strings.Contains(err.Error(), "use_path_style") {
+		t.Errorf("got %v, want acceleration with path style to be rejected", err)
+	}
+}
@@ -0,0 +1,78 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+)
+
+// maxInvalidationPaths is the amount of keys above which the whole distribution is invalidated instead
+const maxInvalidationPaths = 1000
+
+// invalidationPaths returns the configured paths, or the paths of the uploaded keys when none are configured
+func (fc S3FileConfig) invalidationPaths(keys []string) []string {
+	if len(fc.InvalidationPaths) > 0 {
+		return fc.InvalidationPaths
+	} else if len(keys) > maxInvalidationPaths {
+		return []string{"/*"}
+	}
+
+	paths := make([]string, 0, len(keys))
+	for _, key := range keys {
+		paths = append(paths, "/"+strings.TrimPrefix(key, "/"))
+	}
+
+	return paths
+}
+
+func invalidationInput(distributionID string, paths []string) *cloudfront.CreateInvalidationInput {
+	return &cloudfront.CreateInvalidationInput{
+		DistributionId: aws.String(distributionID),
+		InvalidationBatch: &types.InvalidationBatch{
+			CallerReference: aws.String(fmt.Sprintf("zen-%d", time.Now().UnixNano())),
+			Paths: &types.Paths{
+				Items:    paths,
+				Quantity: aws.Int32(int32(len(paths))),
+			},
+		},
+	}
+}
+
+// invalidateCloudFront creates an invalidation on the configured distribution for the uploaded keys
+func (fc S3FileConfig) invalidateCloudFront(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext, keys []string) error {
+	distributionID, err := interpolateAtRuntime(target, runCtx, fc.CloudFrontDistributionID)
+	if err != nil {
+		return fmt.Errorf("interpolating cloudfront distribution id: %w", err)
+	}
+
+	paths := fc.invalidationPaths(keys)
+	if len(paths) == 0 {
+		target.Debugln("nothing was uploaded, skipping cloudfront invalidation")
+		return nil
+	}
+
+	if runCtx.DryRun {
+		target.Infoln("invalidate cloudfront distribution %s: %s", distributionID, strings.Join(paths, ", "))
+		return nil
+	}
+
+	cfg, err := newAwsConfig(target, runCtx, fc, S3Destination{})
+	if err != nil {
+		return fmt.Errorf("loading aws config: %w", err)
+	}
+
+	out, err := cloudfront.NewFromConfig(cfg).CreateInvalidation(context.TODO(), invalidationInput(distributionID, paths))
+	if err != nil {
+		return fmt.Errorf("creating cloudfront invalidation: %w", err)
+	}
+
+	target.Debugln("created cloudfront invalidation %s", aws.ToString(out.Invalidation.Id))
+	return nil
+}
@@ -0,0 +1,58 @@
+package s3
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+func TestInvalidationFromUploadedKeys(t *testing.T) {
+	fc := testConfig("site")
+
+	input := invalidationInput("E123", fc.invalidationPaths([]string{"site/index.html", "site/css/site.css"}))
+	if got := aws.ToString(input.DistributionId); got != "E123" {
+		t.Errorf("got distribution %q", got)
+	}
+	want := []string{"/site/index.html", "/site/css/site.css"}
+	if got := input.InvalidationBatch.Paths.Items; !reflect.DeepEqual(got, want) {
+		t.Errorf("got paths %v, want %v", got, want)
+	}
+	if got := aws.ToInt32(input.InvalidationBatch.Paths.Quantity); got != 2 {
+		t.Errorf("got quantity %d, want 2", got)
+	}
+
+	keys := []string{}
+	for i := 0; i <= maxInvalidationPaths; i++ {
+		keys = append(keys, fmt.Sprintf("site/%d.txt", i))
+	}
+	if got := fc.invalidationPaths(keys); !reflect.DeepEqual(got, []string{"/*"}) {
+		t.Errorf("got %d paths for too many keys, want the whole distribution", len(got))
+	}
+
+	fc.InvalidationPaths = []string{"/site/*"}
+	if got := fc.invalidationPaths(keys); !reflect.DeepEqual(got, fc.InvalidationPaths) {
+		t.Errorf("got %v, want the configured paths", got)
+	}
+}
+
+func TestInvalidationDryRun(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.CloudFrontDistributionID = "E123"
+	fc.MaxParallel = new(int)
+	*fc.MaxParallel = 1
+	target := testTarget(t, fc, map[string]string{"a.txt": "a"})
+
+	if err := runScript(t, fc, "deploy", target, &zen_targets.RuntimeContext{DryRun: true}); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if !logged(target, "invalidate cloudfront distribution E123: /site/a.txt") {
+		t.Errorf("dry run did not print the invalidation: %s", strings.Join(target.Logs, "\n"))
+	}
+}
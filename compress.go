@@ -0,0 +1,41 @@
+package s3
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// uncompressedSizeMetadata is the user metadata holding the size of a file before compression (x-amz-meta-uncompressed-size)
+const uncompressedSizeMetadata = "uncompressed-size"
+
+// compressFile gzips src into a temporary file in dir, positioned at its start. An empty dir is the temp dir of the system.
+// The caller is responsible for removing it with removeTempFile.
+func compressFile(src io.Reader, dir string) (*os.File, error) {
+	tmp, err := os.CreateTemp(dir, "zen-s3-*.gz")
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(tmp)
+	if _, err := io.Copy(gz, src); err != nil {
+		removeTempFile(tmp)
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		removeTempFile(tmp)
+		return nil, err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		removeTempFile(tmp)
+		return nil, err
+	}
+
+	return tmp, nil
+}
+
+func removeTempFile(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
@@ -0,0 +1,82 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompressRecordsTheUncompressedSize(t *testing.T) {
+	fc := testConfig("site")
+	fc.Compress = true
+	content := strings.Repeat("compressible ", 100)
+
+	fake, _ := deployFiles(t, fc, map[string]string{"a.txt": content})
+	obj := fake.object("site/a.txt")
+	if got := obj.metadata[uncompressedSizeMetadata]; got != "1300" {
+		t.Errorf("got uncompressed size %q, want 1300", got)
+	}
+	if obj.contentEncoding != "gzip" {
+		t.Errorf("got content encoding %q", obj.contentEncoding)
+	}
+	if len(obj.body) >= len(content) {
+		t.Errorf("stored %d bytes, want less than the %d of the file", len(obj.body), len(content))
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(obj.body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(gz); err != nil || string(got) != content {
+		t.Errorf("stored body does not decompress to the file: %v", err)
+	}
+}
+
+func TestTransformedUploadsUseTheTempDir(t *testing.T) {
+	for name, transform := range map[string]func(*S3FileConfig){
+		"compress": func(fc *S3FileConfig) { fc.Compress = true },
+		"archive":  func(fc *S3FileConfig) { fc.Archive, fc.ArchiveKey = archiveZip, "site.zip" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, failing := range []bool{false, true} {
+				fake := newFakeS3()
+				useFake(t, fake)
+
+				fc := testConfig("site")
+				fc.TempDir = filepath.Join(t.TempDir(), "scratch")
+				transform(&fc)
+
+				staged := 0
+				fake.hook = func(ctx context.Context, op, key string) error {
+					if op != "PutObject" {
+						return nil
+					}
+					entries, _ := os.ReadDir(fc.TempDir)
+					staged += len(entries)
+					if failing {
+						return accessDeniedError()
+					}
+					return nil
+				}
+
+				target := testTarget(t, fc, map[string]string{"a.txt": strings.Repeat("compressible ", 100)})
+				err := runScript(t, fc, "deploy", target, nil)
+				if failing != (err != nil) {
+					t.Fatalf("failing %v: got %v", failing, err)
+				}
+
+				if staged == 0 {
+					t.Errorf("failing %v: nothing was staged in the temp dir", failing)
+				}
+				if entries, _ := os.ReadDir(fc.TempDir); len(entries) != 0 {
+					t.Errorf("failing %v: %d files left in the temp dir", failing, len(entries))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// deleteIfMatch deletes the objects one by one, each only if it still has the etag it was deployed with,
+// so an object overwritten since, e.g. by another deploy, is kept. Objects without a known etag are deleted as is.
+func deleteIfMatch(ctx context.Context, target *zen_targets.Target, client s3API, bucket string, batch []remoteObject, onDeleted func(ObjectEvent)) []error {
+	errs := []error{}
+	for _, obj := range batch {
+		var optFns []func(*s3.Options)
+		if obj.etag != "" {
+			// this version of the sdk has no IfMatch on deletes, so the header is set directly
+			optFns = append(optFns, func(o *s3.Options) {
+				o.APIOptions = append(o.APIOptions, smithyhttp.SetHeaderValue("If-Match", obj.etag))
+			})
+		} else {
+			target.Debugln("s3://%s/%s: no etag recorded, deleting it unconditionally", bucket, obj.key)
+		}
+
+		start := time.Now()
+		_, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(obj.key),
+		}, optFns...)
+		if isPreconditionFailed(err) {
+			err = fmt.Errorf("not deleting %q, it changed since it was deployed with etag %s", obj.key, obj.etag)
+		} else if isNotFound(err) {
+			// removing is idempotent, like in deleteBatch
+			target.Debugln("s3://%s/%s: already absent", bucket, obj.key)
+			err = nil
+		} else if err != nil {
+			err = fmt.Errorf("failed to delete %q, %v", obj.key, err)
+		}
+		notifyDeleted(onDeleted, bucket, []remoteObject{obj}, time.Since(start), nil, err)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
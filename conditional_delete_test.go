@@ -0,0 +1,57 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+func TestConditionalDeleteSendsIfMatch(t *testing.T) {
+	target := &zen_targets.Target{Name: "site", Env: map[string]string{}}
+	batch := []remoteObject{{key: "site/a.txt", etag: `"etag-a"`}, {key: "site/b.txt"}}
+
+	headers := sentHeaders(t, testConfig("site"), func(ctx context.Context, client *s3.Client) {
+		if errs := deleteIfMatch(ctx, target, client, testBucket, batch, func(ObjectEvent) {}); len(errs) != 0 {
+			t.Errorf("delete: %v", errs)
+		}
+	})
+	if len(headers) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(headers))
+	}
+	if got := headers[0].Get("If-Match"); got != `"etag-a"` {
+		t.Errorf("deleted site/a.txt with If-Match %q, want its deployed etag", got)
+	}
+	if got := headers[1].Get("If-Match"); got != "" {
+		t.Errorf("deleted site/b.txt with If-Match %q, want it unconditional", got)
+	}
+}
+
+func TestConditionalDeletePreconditionFailed(t *testing.T) {
+	fc := testConfig("site")
+	fc.RecordManifest = true
+	fc.ConditionalDelete = true
+	fake, target := deployFiles(t, fc, map[string]string{"a.txt": "a", "b.txt": "b"})
+
+	// b.txt was overwritten by another deploy since
+	fake.fail("DeleteObject", "site/b.txt", responseError(http.StatusPreconditionFailed, nil, &smithy.GenericAPIError{Code: "PreconditionFailed"}))
+
+	err := runScript(t, fc, "remove", target, nil)
+	if err == nil || !strings.Contains(err.Error(), `not deleting "site/b.txt", it changed since it was deployed`) {
+		t.Fatalf("got %v, want the precondition failure of site/b.txt", err)
+	}
+	if strings.Contains(err.Error(), "a.txt") {
+		t.Errorf("got %v, site/a.txt was unchanged", err)
+	}
+	if got, want := fake.stored(), []string{"site/b.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("left %v, want %v", got, want)
+	}
+	if n := fake.count("DeleteObjects"); n != 0 {
+		t.Errorf("made %d unconditional batch deletes", n)
+	}
+}
@@ -0,0 +1,25 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"time"
+)
+
+// contentKey computes the key inline content is uploaded to, which is relative to the prefix like files are
+func (d *deployment) contentKey(suffix string) string {
+	return applyKeyCase(d.fc.KeyCase, path.Join(d.prefix, suffix))
+}
+
+// uploadContent uploads the inline content configured for the key suffix
+func (d *deployment) uploadContent(ctx context.Context, suffix string) (string, error) {
+	content, err := interpolateAtRuntime(d.target, d.runCtx, d.fc.Content[suffix])
+	if err != nil {
+		return "", fmt.Errorf("interpolating content of %q: %w", suffix, err)
+	}
+
+	body := bytes.NewReader([]byte(content))
+	return d.send(ctx, d.contentKey(suffix), suffix, body, body.Size(), time.Time{}, nil)
+}
@@ -0,0 +1,29 @@
+package s3
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInlineContent(t *testing.T) {
+	fc := testConfig("site")
+	fc.Content = map[string]string{"meta/build.json": `{"version": "{VERSION}"}`}
+	fake := newFakeS3()
+	useFake(t, fake)
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+	target.Env["VERSION"] = "1.2.3"
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	if got, want := fake.stored(), []string{"site/index.html", "site/meta/build.json"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("stored %v, want %v", got, want)
+	}
+	if got := string(fake.object("site/meta/build.json").body); got != `{"version": "1.2.3"}` {
+		t.Errorf("inline content holds %q", got)
+	}
+	if got := fake.object("site/meta/build.json").contentType; got != "application/json" {
+		t.Errorf("inline content has content type %q", got)
+	}
+}
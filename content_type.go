@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+const htmlContentType = "text/html; charset=utf-8"
+
+// contentType detects the Content-Type of the file at rel from its extension, unless it is overridden.
+// Extensionless files matching html_paths are pretty URL pages, served as html.
+// The archive of the outs gets the type of its format. Files whose type cannot be detected get the default
+// content type, if any.
+func (fc S3FileConfig) contentType(rel string) string {
+	if contentType, ok := matchGlobValue(fc.ContentTypes, rel); ok {
+		return contentType
+	}
+
+	if fc.Archive != "" && rel == fc.ArchiveKey {
+		return archiveContentTypes[fc.Archive]
+	}
+
+	ext := filepath.Ext(rel)
+	if ext == "" {
+		if matchesAnyGlob(fc.HTMLPaths, rel) {
+			return htmlContentType
+		}
+		return fc.DefaultContentType
+	}
+
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+
+	return fc.DefaultContentType
+}
+
+// resolveContentTypes merges the overrides shared through the env var named by content_types_env, formatted as
+// comma separated glob=type pairs, with the ones of the target, which take precedence. Values can be interpolated.
+func (fc S3FileConfig) resolveContentTypes(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) (map[string]string, error) {
+	resolved := map[string]string{}
+
+	if fc.ContentTypesEnv != "" {
+		for _, entry := range strings.Split(target.Env[fc.ContentTypesEnv], ",") {
+			if strings.TrimSpace(entry) == "" {
+				continue
+			}
+
+			pattern, contentType, ok := strings.Cut(entry, "=")
+			if !ok {
+				return nil, fmt.Errorf("%s: expected glob=type, got %q", fc.ContentTypesEnv, entry)
+			}
+			resolved[strings.TrimSpace(pattern)] = strings.TrimSpace(contentType)
+		}
+	}
+
+	for pattern, contentType := range fc.ContentTypes {
+		interpolated, err := interpolateAtRuntime(target, runCtx, contentType)
+		if err != nil {
+			return nil, fmt.Errorf("interpolating content type of %q: %w", pattern, err)
+		}
+		resolved[pattern] = interpolated
+	}
+
+	return resolved, nil
+}
+
+// uploadsLast reports whether rel is uploaded after the others, being an html entry point with html_last or
+// matching upload_last. Uploading them last avoids serving pages referencing assets that are not there yet.
+func (fc S3FileConfig) uploadsLast(rel string) bool {
+	return (fc.HTMLLast && strings.HasPrefix(fc.contentType(rel), "text/html")) || matchesAnyGlob(fc.UploadLast, rel)
+}
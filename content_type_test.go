@@ -0,0 +1,168 @@
+package s3
+
+import (
+	"context"
+	"mime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestHTMLPaths(t *testing.T) {
+	fc := testConfig("site")
+	fc.HTMLPaths = []string{"about", "blog/*"}
+
+	fake, _ := deployFiles(t, fc, map[string]string{"about": "<html></html>", "blog/first-post": "<html></html>", "LICENSE": "MIT"})
+	for key, want := range map[string]string{
+		"site/about":           htmlContentType,
+		"site/blog/first-post": htmlContentType,
+		"site/LICENSE":         "",
+	} {
+		if got := aws.ToString(fake.putInput(key).ContentType); got != want {
+			t.Errorf("%s got content type %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestDefaultContentType(t *testing.T) {
+	fc := testConfig("site")
+	fc.DefaultContentType = "application/octet-stream"
+	fc.ContentTypes = map[string]string{"VERSION": "text/plain"}
+
+	fake, _ := deployFiles(t, fc, map[string]string{"LICENSE": "MIT", "VERSION": "1.0.0", "app.js": "app", "data.unknownext": "data"})
+	for key, want := range map[string]string{
+		"site/LICENSE":         "application/octet-stream",
+		"site/VERSION":         "text/plain",
+		"site/app.js":          mime.TypeByExtension(".js"),
+		"site/data.unknownext": "application/octet-stream",
+	} {
+		if got := aws.ToString(fake.putInput(key).ContentType); got != want {
+			t.Errorf("%s got content type %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestHTMLLast(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	fake.hook = func(ctx context.Context, op, key string) error {
+		// slow assets, which html must still wait for
+		if op == "PutObject" && !strings.HasSuffix(key, ".html") {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return nil
+	}
+
+	fc := testConfig("site")
+	fc.HTMLLast = true
+	fc.MaxParallel = intPtr(8)
+	target := testTarget(t, fc, map[string]string{
+		"index.html":  "<html></html>",
+		"about.html":  "<html></html>",
+		"app.js":      "app",
+		"style.css":   "style",
+		"img/a.png":   "a",
+		"img/b.png":   "b",
+		"fonts/c.ttf": "c",
+	})
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	puts := []string{}
+	for _, input := range fake.inputs("PutObject") {
+		puts = append(puts, aws.ToString(input.(*s3.PutObjectInput).Key))
+	}
+	if len(puts) != 7 {
+		t.Fatalf("uploaded %v, want 7 objects", puts)
+	}
+	for i, key := range puts {
+		if isHTML := strings.HasSuffix(key, ".html"); isHTML != (i >= 5) {
+			t.Fatalf("uploaded in order %v, want the html files last", puts)
+		}
+	}
+}
+
+func TestContentTypesFromEnv(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.ContentTypesEnv = "SHARED_CONTENT_TYPES"
+	fc.ContentTypes = map[string]string{"*.wasm": "{WASM_TYPE}"}
+	target := testTarget(t, fc, map[string]string{"feed": "<rss/>", "data.bin": "data", "app.wasm": "wasm"})
+	target.Env["SHARED_CONTENT_TYPES"] = "feed=application/rss+xml, *.bin = application/x-custom, *.wasm=text/plain"
+	target.Env["WASM_TYPE"] = "application/wasm"
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	for key, want := range map[string]string{
+		"site/feed":     "application/rss+xml",
+		"site/data.bin": "application/x-custom",
+		// the target overrides take precedence over the shared ones
+		"site/app.wasm": "application/wasm",
+	} {
+		if got := aws.ToString(fake.putInput(key).ContentType); got != want {
+			t.Errorf("%s got content type %q, want %q", key, got, want)
+		}
+	}
+
+	target.Env["SHARED_CONTENT_TYPES"] = "feed"
+	if err := runScript(t, fc, "deploy", target, nil); err == nil || !strings.Contains(err.Error(), "expected glob=type") {
+		t.Errorf("got %v, want the malformed entry to be reported", err)
+	}
+}
+
+func TestUploadLastWaitsForTheOtherUploads(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	var mu sync.Mutex
+	finished, early := 0, []string{}
+	fake.hook = func(ctx context.Context, op, key string) error {
+		if op != "PutObject" {
+			return nil
+		}
+		if strings.HasSuffix(key, ".html") || strings.HasSuffix(key, "manifest.json") {
+			mu.Lock()
+			if finished != 4 {
+				early = append(early, key)
+			}
+			mu.Unlock()
+			return nil
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		finished++
+		mu.Unlock()
+		return nil
+	}
+
+	fc := testConfig("site")
+	fc.UploadLast = []string{"*.html", "manifest.json"}
+	fc.MaxParallel = intPtr(8)
+	target := testTarget(t, fc, map[string]string{
+		"index.html":    "<html></html>",
+		"manifest.json": "{}",
+		"app.1a2b.js":   "app",
+		"app.3c4d.css":  "style",
+		"logo.png":      "png",
+		"data.json":     "{}",
+	})
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	if len(early) != 0 {
+		t.Errorf("%v started before the other uploads finished", early)
+	}
+	if n := fake.count("PutObject"); n != 6 {
+		t.Errorf("uploaded %d files, want 6", n)
+	}
+}
@@ -0,0 +1,60 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// ErrNoCredentials is returned before touching any object when no aws credentials could be found
+var ErrNoCredentials = errors.New("no aws credentials found, set access_key_id, the AWS_* variables of the target env or a profile, or use anonymous for public buckets")
+
+// checkCredentials makes sure the config resolves to credentials, which the sdk would otherwise
+// only find out about on the first request
+func checkCredentials(ctx context.Context, cfg aws.Config) error {
+	if cfg.Credentials == nil {
+		return ErrNoCredentials
+	}
+
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrNoCredentials, err)
+	}
+
+	return nil
+}
+
+// envCredentials resolves the credentials from the env of the target, made of env, pass_env and secret_env,
+// so the AWS_* variables of the process env are never looked at. Static keys come first, then a shared config
+// profile, AWS_PROFILE or default, read from the files the target points to or the default ones. Setting the
+// profile explicitly stops the sdk from reading keys and web identity tokens from the process env, leaving it
+// the profile and the container and instance roles to fall back to.
+func envCredentials(env map[string]string) []func(*config.LoadOptions) error {
+	if id, secret := env["AWS_ACCESS_KEY_ID"], env["AWS_SECRET_ACCESS_KEY"]; id != "" && secret != "" {
+		return []func(*config.LoadOptions) error{
+			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(id, secret, env["AWS_SESSION_TOKEN"])),
+		}
+	}
+
+	profile := env["AWS_PROFILE"]
+	if profile == "" {
+		profile = "default"
+	}
+	credentialsFile := env["AWS_SHARED_CREDENTIALS_FILE"]
+	if credentialsFile == "" {
+		credentialsFile = config.DefaultSharedCredentialsFilename()
+	}
+	configFile := env["AWS_CONFIG_FILE"]
+	if configFile == "" {
+		configFile = config.DefaultSharedConfigFilename()
+	}
+
+	return []func(*config.LoadOptions) error{
+		config.WithSharedConfigProfile(profile),
+		config.WithSharedCredentialsFiles([]string{credentialsFile}),
+		config.WithSharedConfigFiles([]string{configFile}),
+	}
+}
@@ -0,0 +1,177 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+// isolateCredentials sets ambient credentials in the process env, which the target should never pick up,
+// and turns off the instance role so nothing else provides credentials
+func isolateCredentials(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDAMBIENT")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "ambient-secret")
+	t.Setenv("AWS_PROFILE", "ambient")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "")
+	t.Setenv("AWS_CONFIG_FILE", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+}
+
+func retrieveAccessKeyID(t *testing.T, fc S3FileConfig, env map[string]string) (string, error) {
+	t.Helper()
+
+	target := &zen_targets.Target{Name: "site", Env: env}
+	cfg, err := newAwsConfig(target, &zen_targets.RuntimeContext{}, fc, S3Destination{})
+	if err != nil {
+		return "", err
+	}
+
+	creds, err := cfg.Credentials.Retrieve(context.Background())
+	return creds.AccessKeyID, err
+}
+
+func TestCredentialsComeFromTargetEnv(t *testing.T) {
+	isolateCredentials(t)
+
+	id, err := retrieveAccessKeyID(t, S3FileConfig{Region: "us-east-1"}, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIDTARGET",
+		"AWS_SECRET_ACCESS_KEY": "target-secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if id != "AKIDTARGET" {
+		t.Errorf("got access key id %q, want the one of the target env", id)
+	}
+}
+
+func TestCredentialsIgnoreProcessEnv(t *testing.T) {
+	isolateCredentials(t)
+
+	if id, err := retrieveAccessKeyID(t, S3FileConfig{Region: "us-east-1"}, map[string]string{}); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("got access key id %q and error %v, want %v", id, err, ErrNoCredentials)
+	}
+}
+
+func TestCredentialsProfileFromTargetEnv(t *testing.T) {
+	isolateCredentials(t)
+
+	file := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(file, []byte("[ambient]\naws_access_key_id = AKIDAMBIENTPROFILE\naws_secret_access_key = ambient\n\n[deploy]\naws_access_key_id = AKIDPROFILE\naws_secret_access_key = profile-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := retrieveAccessKeyID(t, S3FileConfig{Region: "us-east-1"}, map[string]string{
+		"AWS_PROFILE":                 "deploy",
+		"AWS_SHARED_CREDENTIALS_FILE": file,
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if id != "AKIDPROFILE" {
+		t.Errorf("got access key id %q, want the one of the profile of the target env", id)
+	}
+}
+
+func TestStaticCredentialsOverrideTheDefaultChain(t *testing.T) {
+	isolateCredentials(t)
+
+	fc := S3FileConfig{Region: "us-east-1", AccessKeyID: "{DEPLOY_KEY_ID}", SecretAccessKey: "{DEPLOY_SECRET}"}
+	id, err := retrieveAccessKeyID(t, fc, map[string]string{
+		"DEPLOY_KEY_ID":         "AKIDSTATIC",
+		"DEPLOY_SECRET":         "static-secret",
+		"AWS_ACCESS_KEY_ID":     "AKIDTARGET",
+		"AWS_SECRET_ACCESS_KEY": "target-secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if id != "AKIDSTATIC" {
+		t.Errorf("got access key id %q, want the static one", id)
+	}
+}
+
+func TestAnonymousRequestsAreUnsigned(t *testing.T) {
+	isolateCredentials(t)
+
+	var authorization []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	list := func(fc S3FileConfig, env map[string]string) {
+		cfg, err := newAwsConfig(&zen_targets.Target{Name: "site", Env: env}, &zen_targets.RuntimeContext{}, fc, S3Destination{})
+		if err != nil {
+			t.Fatalf("anonymous %t: %v", fc.Anonymous, err)
+		}
+		client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+			o.EndpointResolver = s3.EndpointResolverFromURL(server.URL)
+			o.UsePathStyle = true
+			o.Retryer = aws.NopRetryer{}
+		})
+		client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{Bucket: aws.String("public")})
+	}
+
+	// neither the ambient credentials nor any of the target are used
+	list(S3FileConfig{Region: "us-east-1", Anonymous: true}, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIDTARGET",
+		"AWS_SECRET_ACCESS_KEY": "target-secret",
+	})
+	list(S3FileConfig{Region: "us-east-1"}, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIDTARGET",
+		"AWS_SECRET_ACCESS_KEY": "target-secret",
+	})
+
+	if len(authorization) != 2 {
+		t.Fatalf("got %d requests, want 2", len(authorization))
+	}
+	if authorization[0] != "" {
+		t.Errorf("the anonymous request was signed with %q", authorization[0])
+	}
+	if !strings.Contains(authorization[1], "Credential=AKIDTARGET/") {
+		t.Errorf("the request with credentials was signed with %q", authorization[1])
+	}
+}
+
+func TestAnonymousWithoutAnyCredentials(t *testing.T) {
+	isolateCredentials(t)
+
+	if _, err := newAwsConfig(&zen_targets.Target{Name: "site", Env: map[string]string{}}, &zen_targets.RuntimeContext{}, S3FileConfig{Region: "us-east-1", Anonymous: true}, S3Destination{}); err != nil {
+		t.Errorf("anonymous config failed without credentials: %v", err)
+	}
+
+	fc := testConfig("site")
+	fc.Anonymous = true
+	fc.AccessKeyID = "AKID"
+	fc.SecretAccessKey = "secret"
+	if _, err := fc.GetTargets(&zen_targets.TargetConfigContext{}); err == nil || !strings.Contains(err.Error(), "anonymous cannot be set together with access_key_id") {
+		t.Errorf("got %v, want anonymous with static credentials to be rejected", err)
+	}
+}
+
+func TestMissingCredentialsFailBeforeUploading(t *testing.T) {
+	isolateCredentials(t)
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.Anonymous = false
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+
+	if err := runScript(t, fc, "deploy", target, nil); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("got %v, want %v", err, ErrNoCredentials)
+	}
+	if len(fake.calls) != 0 {
+		t.Errorf("made %v before reporting the missing credentials", fake.calls)
+	}
+}
@@ -0,0 +1,22 @@
+package s3
+
+import (
+	"fmt"
+	"time"
+)
+
+// now is the clock date partitions are computed from
+var now = time.Now
+
+// datePartition formats the current time in the configured timezone, UTC by default, with the configured layout
+func (fc S3FileConfig) datePartition() (string, error) {
+	loc := time.UTC
+	if fc.DatePrefixTimezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(fc.DatePrefixTimezone); err != nil {
+			return "", fmt.Errorf("loading date_prefix_timezone: %w", err)
+		}
+	}
+
+	return now().In(loc).Format(fc.DatePrefixLayout), nil
+}
@@ -0,0 +1,66 @@
+package s3
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+func fixedClock(t *testing.T, at time.Time) {
+	prev := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = prev })
+}
+
+func TestDatePartitionTimezone(t *testing.T) {
+	// late on March 31 in UTC, already April 1 in Berlin
+	fixedClock(t, time.Date(2024, 3, 31, 23, 30, 0, 0, time.UTC))
+
+	for _, tc := range []struct {
+		layout, timezone, want string
+	}{
+		{"2006/01/02", "", "2024/03/31"},
+		{"2006/01/02", "UTC", "2024/03/31"},
+		{"2006/01/02", "Europe/Berlin", "2024/04/01"},
+		{"2006/01/02/15", "America/New_York", "2024/03/31/19"},
+		{"dt=2006-01-02", "Asia/Tokyo", "dt=2024-04-01"},
+	} {
+		fc := testConfig("logs")
+		fc.DatePrefixLayout = tc.layout
+		fc.DatePrefixTimezone = tc.timezone
+		if got, err := fc.datePartition(); err != nil || got != tc.want {
+			t.Errorf("%q in %q: got %q, %v, want %q", tc.layout, tc.timezone, got, err, tc.want)
+		}
+	}
+}
+
+func TestDatePrefix(t *testing.T) {
+	fixedClock(t, time.Date(2024, 3, 31, 23, 30, 0, 0, time.UTC))
+
+	fc := testConfig("logs")
+	fc.DatePrefixLayout = "2006/01/02"
+	fc.DatePrefixTimezone = "Europe/Berlin"
+	fake, _ := deployFiles(t, fc, map[string]string{"app.log": "log"})
+
+	if got, want := fake.stored(), []string{"logs/2024/04/01/app.log"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored %v, want %v", got, want)
+	}
+}
+
+func TestDatePrefixTimezoneValidation(t *testing.T) {
+	fc := testConfig("logs")
+	fc.DatePrefixTimezone = "Europe/Berlin"
+	if _, err := fc.GetTargets(&zen_targets.TargetConfigContext{}); err == nil || !strings.Contains(err.Error(), "requires date_prefix_layout") {
+		t.Errorf("got %v, want the missing layout to be reported", err)
+	}
+
+	fc.DatePrefixLayout = "2006/01/02"
+	fc.DatePrefixTimezone = "Mars/Olympus_Mons"
+	if _, err := fc.GetTargets(&zen_targets.TargetConfigContext{}); err == nil || !strings.Contains(err.Error(), "date_prefix_timezone") {
+		t.Errorf("got %v, want the unknown timezone to be reported", err)
+	}
+}
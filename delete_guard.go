@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// countObjects counts the objects stored under the prefix, the whole bucket when it is empty
+func countObjects(ctx context.Context, client s3API, bucket, prefix string) (int, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+	}
+	if p := strings.Trim(prefix, "/"); p != "" {
+		input.Prefix = aws.String(p + "/")
+	}
+
+	count := 0
+	paginator := s3.NewListObjectsV2Paginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("counting objects under %q: %w", prefix, err)
+		}
+		count += len(page.Contents)
+	}
+
+	return count, nil
+}
+
+// checkDeletePercent refuses to remove more than max_delete_percent of the objects under the prefix,
+// which usually means the prefix is not the one it was meant to be
+func (fc S3FileConfig) checkDeletePercent(ctx context.Context, dest destination, objects []remoteObject) error {
+	total, err := countObjects(ctx, dest.client, dest.bucket, dest.prefix)
+	if err != nil {
+		return err
+	} else if total == 0 {
+		return nil
+	}
+
+	if percent := float64(len(objects)) * 100 / float64(total); percent > float64(fc.MaxDeletePercent) {
+		return fmt.Errorf("refusing to remove %d of the %d objects in %s (%.0f%%), above max_delete_percent of %d%%. Set force to remove them anyway",
+			len(objects), total, dest, percent, fc.MaxDeletePercent)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package s3
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMaxDeletePercent(t *testing.T) {
+	for _, force := range []bool{false, true} {
+		t.Run(fmt.Sprintf("force=%v", force), func(t *testing.T) {
+			fake := newFakeS3()
+			useFake(t, fake)
+
+			fc := testConfig("site")
+			fc.RecordManifest = true
+			fc.MaxDeletePercent = 50
+			fc.Force = force
+
+			files := map[string]string{}
+			for i := 0; i < 9; i++ {
+				files[fmt.Sprintf("page%d.html", i)] = "<html></html>"
+			}
+			target := testTarget(t, fc, files)
+
+			if err := runScript(t, fc, "deploy", target, nil); err != nil {
+				t.Fatalf("deploy: %v", err)
+			}
+			fake.put("site/other.txt", "not deployed by the target")
+
+			// the 9 files and the manifest are 90% of the objects under the prefix
+			err := runScript(t, fc, "remove", target, nil)
+			if force {
+				if err != nil {
+					t.Fatalf("remove: %v", err)
+				}
+				if got := fake.stored(); len(got) != 1 {
+					t.Errorf("left %v, want only the object the target did not deploy", got)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), "max_delete_percent") {
+				t.Fatalf("got error %v, want the removal refused", err)
+			}
+			if n := fake.count("DeleteObjects"); n != 0 {
+				t.Errorf("made %d DeleteObjects calls", n)
+			}
+		})
+	}
+}
+
+func TestMaxDeletePercentRequiresManifest(t *testing.T) {
+	fc := testConfig("site")
+	fc.MaxDeletePercent = 50
+	if err := fc.validate(); err == nil || !strings.Contains(err.Error(), "record_manifest") {
+		t.Errorf("got error %v, want record_manifest required", err)
+	}
+}
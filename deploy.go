@@ -0,0 +1,770 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// deployment holds the state shared by all the uploads of a single deploy run
+type deployment struct {
+	fc     S3FileConfig
+	target *zen_targets.Target
+	runCtx *zen_targets.RuntimeContext
+	client s3API
+	// uploader is nil on dry runs
+	uploader s3Uploader
+	// partSize is the size above which the uploader splits uploads in parts
+	partSize int64
+	bucket   string
+	prefix   string
+	hooks    UploadOptions
+	tracer   trace.Tracer
+	// receipt records the content of the objects, nil when not producing one
+	receipt *deployReceipt
+	// grants set in the config, or inherited from the bucket acl
+	grants objectGrants
+	// aclsDisabled drops every acl and grant, for buckets enforcing bucket owner ownership
+	aclsDisabled bool
+	// retainUntil is the parsed object lock retention date
+	retainUntil time.Time
+	// version the objects are uploaded under, and the key of the object pointing to it
+	version    string
+	pointerKey string
+	// manifestKey stays under the prefix of the destination, so remove finds it whatever the version or date
+	manifestKey string
+	// versions the objects were uploaded as, when capturing them, and their etags, when recording a manifest.
+	// Both are guarded by versionsMu.
+	versionsMu sync.Mutex
+	versions   map[string]string
+	etags      map[string]string
+}
+
+func (fc S3FileConfig) deploy(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) error {
+	target.SetStatus("Uploading to s3 (%s)", target.Qn())
+
+	ctx, span := fc.tracer().Start(context.TODO(), "s3.deploy", trace.WithAttributes(attribute.String("zen.target", target.Qn())))
+	err := fc.deployAll(ctx, target, runCtx)
+	status := summaryUploaded
+	if err != nil {
+		status = summaryFailed
+	}
+	endSpan(span, status, err)
+
+	return err
+}
+
+// deployAll uploads the outs to every destination
+func (fc S3FileConfig) deployAll(ctx context.Context, target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) error {
+	keyTemplate, err := fc.resolveKeyTemplate(target, runCtx)
+	if err != nil {
+		return fmt.Errorf("interpolating key template: %w", err)
+	}
+	fc.KeyTemplate = keyTemplate
+
+	if fc.ContentTypes, err = fc.resolveContentTypes(target, runCtx); err != nil {
+		return err
+	}
+
+	// The outs are the same for every destination
+	outs, err := fc.uploadableOuts(target)
+	if err != nil {
+		return err
+	}
+	if len(outs) == 0 && len(fc.Content) == 0 && len(fc.Redirects) == 0 && !fc.AllowEmpty {
+		return fmt.Errorf("srcs of %s matched no files, set allow_empty if deploying nothing is expected", target.Qn())
+	}
+	if err := checkOutsExist(outs); err != nil {
+		return err
+	}
+	if fc.TempDir != "" {
+		if err := os.MkdirAll(fc.TempDir, os.ModePerm); err != nil {
+			return fmt.Errorf("creating temp dir: %w", err)
+		}
+	}
+	if fc.MaxFileSize != "" {
+		maxFileSize, _ := parseByteSize(fc.MaxFileSize)
+		if err := checkOutsSize(outs, maxFileSize); err != nil {
+			return err
+		}
+	}
+
+	dests, err := fc.destinations(target, runCtx)
+	if err != nil {
+		return err
+	}
+
+	var summary *deploySummary
+	if fc.Summary != "" {
+		summary = &deploySummary{Files: []fileSummary{}}
+	}
+
+	var receipt *deployReceipt
+	if fc.Receipt != "" || fc.UploadReceipt {
+		receipt = newDeployReceipt()
+	}
+
+	// Destinations are deployed one after the other, each with the configured upload concurrency
+	uploaded := []string{}
+	errs := []error{}
+	for _, dest := range dests {
+		keys, err := fc.deployTo(ctx, target, runCtx, dest, outs, summary, receipt)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deploying to %s: %w", dest, err))
+			continue
+		}
+		uploaded = append(uploaded, keys...)
+	}
+
+	// The summary is written for failed deploys too, they are the ones worth looking at
+	if summary != nil {
+		if err := summary.write(target.Cwd, fc.Summary); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// Unlike the summary, a receipt is only worth comparing when the whole deploy went through
+	if receipt != nil {
+		if err := fc.storeReceipt(ctx, target, runCtx, dests, receipt); err != nil {
+			return err
+		}
+	}
+
+	if fc.CloudFrontDistributionID != "" {
+		slices.Sort(uploaded)
+		if err := fc.invalidateCloudFront(target, runCtx, slices.Compact(uploaded)); err != nil {
+			return err
+		}
+	}
+
+	// The smoke test goes last, so it sees the invalidated distribution
+	if fc.SmokeTestURL != "" {
+		return fc.smokeTest(target, runCtx)
+	}
+
+	return nil
+}
+
+// newDeployment sets up the deployment of the target to dest, under the date and version partitions of its prefix when set.
+// Remove goes through it too, so it derives the same keys the deploy uploaded to.
+func (fc S3FileConfig) newDeployment(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext, dest destination, receipt *deployReceipt) (*deployment, error) {
+	d := &deployment{
+		fc:       fc,
+		target:   target,
+		runCtx:   runCtx,
+		client:   dest.client,
+		bucket:   dest.bucket,
+		prefix:   dest.prefix,
+		hooks:    fc.UploadOptions.withDefaults(target),
+		tracer:   fc.tracer(),
+		receipt:  receipt,
+		partSize: manager.DefaultUploadPartSize,
+		versions: map[string]string{},
+		etags:    map[string]string{},
+
+		manifestKey: fc.manifestKey(dest.prefix),
+	}
+
+	if fc.DatePrefixLayout != "" {
+		partition, err := fc.datePartition()
+		if err != nil {
+			return nil, err
+		}
+		d.prefix = path.Join(d.prefix, partition)
+	}
+
+	if fc.VersionPointer != "" {
+		version, err := interpolateAtRuntime(target, runCtx, fc.Version)
+		if err != nil {
+			return nil, fmt.Errorf("interpolating version: %w", err)
+		} else if version == "" {
+			return nil, fmt.Errorf("version interpolated to an empty value")
+		}
+
+		d.version = version
+		d.pointerKey = fc.versionPointerKey(dest.prefix)
+		d.prefix = path.Join(d.prefix, version)
+	}
+
+	return d, nil
+}
+
+// deployTo uploads the outs to a single destination, returning the keys that were written
+func (fc S3FileConfig) deployTo(ctx context.Context, target *zen_targets.Target, runCtx *zen_targets.RuntimeContext, dest destination, outs []string, summary *deploySummary, receipt *deployReceipt) ([]string, error) {
+	client, bucket := dest.client, dest.bucket
+
+	if fc.Preflight {
+		if err := fc.preflight(target, client, bucket); err != nil {
+			return nil, err
+		}
+	}
+
+	d, err := fc.newDeployment(target, runCtx, dest, receipt)
+	if err != nil {
+		return nil, err
+	}
+
+	if fc.CaptureVersions && !runCtx.DryRun {
+		if err := d.checkVersioning(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// A dry run only plans, so it never needs an uploader
+	if !runCtx.DryRun {
+		// Files are uploaded max_parallel at a time, and the parts of each of them part_concurrency at a time
+		d.uploader = newUploader(client, func(u *manager.Uploader) {
+			u.Concurrency = *fc.PartConcurrency
+			// the parts of failed uploads are kept for the next deploy to resume
+			u.LeavePartsOnError = fc.ResumeUploads
+		})
+	}
+
+	// Buckets enforcing bucket owner ownership reject every acl, canned or granted, set in the config or by rules
+	detectOwnership := fc.DetectObjectOwnership == nil || *fc.DetectObjectOwnership
+	if fc.setsACL() && detectOwnership && bucketOwnerEnforced(ctx, target, client, bucket) {
+		target.Debugln("%s enforces bucket owner object ownership, not setting any acl or grant", bucket)
+		d.aclsDisabled = true
+	} else if grants := fc.grants(); !grants.empty() {
+		d.grants = grants
+	} else if fc.ACL == "" && fc.InheritBucketACL {
+		if d.grants, err = bucketGrants(ctx, client, bucket); err != nil {
+			return nil, err
+		}
+	}
+
+	if fc.ObjectLockMode != "" {
+		retainUntil, err := time.Parse(time.RFC3339, fc.ObjectLockRetainUntil)
+		if err != nil {
+			return nil, fmt.Errorf("object_lock_retain_until must be an RFC3339 timestamp: %w", err)
+		} else if !retainUntil.After(time.Now()) {
+			return nil, fmt.Errorf("object_lock_retain_until %s is not in the future", fc.ObjectLockRetainUntil)
+		}
+		d.retainUntil = retainUntil
+	}
+
+	if fc.Flatten || fc.KeyTemplate != "" {
+		if err := fc.checkKeyCollisions(d.prefix, target.Cwd, outs); err != nil {
+			return nil, err
+		}
+	}
+
+	// Cancelled on the first error when failing fast without draining
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Every key is checked before anything is uploaded, counting the jobs along the way
+	jobCount := 0
+	var outside error
+	d.eachUploadJob(outs, func(job uploadJob) bool {
+		if !underEnforcedPrefix(job.key) {
+			outside = fmt.Errorf("key %q of %q is outside of the enforced prefix %q", job.key, job.rel, enforcedPrefix())
+			return false
+		}
+		jobCount++
+		return true
+	})
+	if outside != nil {
+		return nil, outside
+	}
+
+	// Storage classes with their own concurrency are limited on top of the amount of workers
+	classSems := fc.classSemaphores()
+
+	// Keys written (or that would be written on a dry run) and errors of the failed uploads
+	var mu sync.Mutex
+	uploaded := []string{}
+	uploadedFiles := map[string]string{}
+	var errs multiError
+	var failed atomic.Bool
+
+	var budget *byteBudget
+	if fc.MaxInflightBytes != "" {
+		// already validated in GetTargets
+		maxInflight, _ := parseByteSize(fc.MaxInflightBytes)
+		budget = newByteBudget(maxInflight)
+	}
+
+	// Backs off when S3 asks to slow down, on top of the amount of workers
+	var limiter *adaptiveLimiter
+	if fc.AdaptiveConcurrency {
+		limiter = newAdaptiveLimiter(fc.parallelism(jobCount))
+	}
+
+	// inflight tracks the dispatched jobs that did not complete yet
+	var inflight sync.WaitGroup
+	upload := func(job uploadJob) {
+		defer inflight.Done()
+		if budget != nil {
+			defer budget.release(job.size)
+		}
+
+		// Waiting for a slot of the storage class only holds up this worker, the others keep going
+		if classSem := classSems[fc.objectStorageClass(job.rel)]; classSem != nil {
+			classSem <- struct{}{}
+			defer func() { <-classSem }()
+		}
+
+		spanCtx, span := d.startUploadSpan(ctx, job)
+		var key string
+		var err error
+		if limiter != nil {
+			key, err = d.runThrottled(spanCtx, limiter, job)
+		} else {
+			key, err = job.run(spanCtx)
+		}
+		summary.record(dest, job, key, err)
+		endSpan(span, uploadStatus(key, err), err)
+
+		if err != nil {
+			errs.add(err)
+			failed.Store(true)
+			if fc.FailFast && !fc.DrainOnError {
+				cancel()
+			}
+		} else if key != "" {
+			mu.Lock()
+			defer mu.Unlock()
+			uploaded = append(uploaded, key)
+			if job.file != "" {
+				uploadedFiles[key] = job.file
+			}
+		}
+	}
+
+	// A fixed pool of workers picks up the jobs as they are dispatched, so the amount of goroutines
+	// does not grow with the amount of files
+	work := make(chan uploadJob)
+	var workers sync.WaitGroup
+	for i := 0; i < fc.parallelism(jobCount); i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range work {
+				upload(job)
+			}
+		}()
+	}
+
+	// dispatch hands a job to the workers, returning false once no more should be dispatched
+	dispatch := func(job uploadJob) bool {
+		// Files are only looked at when dispatched
+		if job.file != "" {
+			if info, err := os.Stat(job.file); err == nil {
+				job.size = info.Size()
+			}
+		}
+
+		if budget != nil {
+			budget.acquire(job.size)
+		}
+
+		// Stop dispatching once something failed, running uploads are drained or cancelled below
+		if fc.FailFast && failed.Load() {
+			if budget != nil {
+				budget.release(job.size)
+			}
+			return false
+		}
+
+		inflight.Add(1)
+		work <- job
+		return true
+	}
+
+	// Without ordering, no job waits for the others
+	ordered := fc.HTMLLast || len(fc.UploadLast) > 0
+	stopped := false
+	d.eachUploadJob(outs, func(job uploadJob) bool {
+		if ordered && fc.uploadsLast(job.rel) {
+			return true
+		}
+		stopped = !dispatch(job)
+		return !stopped
+	})
+
+	// The deferred uploads, like html entry points, only start once everything they may reference was uploaded
+	if ordered && !stopped {
+		inflight.Wait()
+		if !failed.Load() {
+			d.eachUploadJob(outs, func(job uploadJob) bool {
+				return !fc.uploadsLast(job.rel) || dispatch(job)
+			})
+		}
+	}
+	close(work)
+
+	// Wait for all uploads to complete
+	workers.Wait()
+
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+
+	// The objects describing the deploy only depend on the files, not on each other
+	auxiliary := []func(ctx context.Context) error{}
+	if fc.SitemapBaseURL != "" {
+		auxiliary = append(auxiliary, func(ctx context.Context) error {
+			key, err := d.uploadSitemap(ctx, outs)
+			if key != "" {
+				mu.Lock()
+				defer mu.Unlock()
+				uploaded = append(uploaded, key)
+			}
+			return err
+		})
+	}
+
+	if fc.RecordManifest && !runCtx.DryRun {
+		keys := make([]string, 0, jobCount+1)
+		d.eachUploadJob(outs, func(job uploadJob) bool {
+			keys = append(keys, job.key)
+			if job.file != "" {
+				keys = append(keys, d.aliasKeys(job.key)...)
+			}
+			return true
+		})
+		if fc.SitemapBaseURL != "" {
+			// uploaded alongside the manifest, so its etag is not recorded and a conditional delete removes it as is
+			keys = append(keys, d.contentKey(sitemapName))
+		}
+
+		auxiliary = append(auxiliary, func(ctx context.Context) error { return d.writeManifest(ctx, keys) })
+	}
+
+	if err := uploadAuxiliary(ctx, auxiliary...); err != nil {
+		return nil, err
+	}
+
+	if d.fc.VerifyDownloadSampleRate > 0 && !runCtx.DryRun {
+		if err := d.verifySample(ctx, uploadedFiles); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only switch to the new version once all of its objects are in place
+	if d.pointerKey != "" {
+		if err := d.writeVersionPointer(ctx); err != nil {
+			return nil, err
+		}
+		uploaded = append(uploaded, d.pointerKey)
+	}
+
+	return uploaded, nil
+}
+
+// uploadJob is a single object to upload
+type uploadJob struct {
+	key string
+	// rel is the path the object settings are matched against
+	rel string
+	// file is the local file being uploaded, empty for inline content
+	file string
+	// size of the file is only known once the job is dispatched
+	size int64
+	run  func(ctx context.Context) (string, error)
+}
+
+// eachUploadJob produces the objects to upload one at a time, the files in outs (or the archive of them) followed by
+// the inline content and the redirect only objects, so that no more than the jobs in flight are held at once.
+// It stops as soon as yield returns false.
+func (d *deployment) eachUploadJob(outs []string, yield func(uploadJob) bool) {
+	// only the paths redirects are set for decide which redirects need an object of their own
+	rels := []string{}
+	if d.fc.Archive != "" {
+		// the outs are packed into a single object instead of being uploaded one by one. The archive does not
+		// exist yet, the size of the outs it packs is what counts towards max_inflight_bytes instead.
+		archiveOuts := outs
+		var size int64
+		for _, out := range archiveOuts {
+			if info, err := os.Stat(out); err == nil {
+				size += info.Size()
+			}
+		}
+
+		rels = append(rels, d.fc.ArchiveKey)
+		if !yield(uploadJob{
+			key:  d.contentKey(d.fc.ArchiveKey),
+			rel:  d.fc.ArchiveKey,
+			size: size,
+			run:  func(ctx context.Context) (string, error) { return d.uploadArchive(ctx, archiveOuts) },
+		}) {
+			return
+		}
+		outs = nil
+	}
+	for _, out := range outs {
+		f, key, rel := out, d.fc.objectKey(d.prefix, d.target.Cwd, out), relPath(d.target.Cwd, out)
+		if _, ok := d.fc.Redirects[rel]; ok {
+			rels = append(rels, rel)
+		}
+		if !yield(uploadJob{
+			key:  key,
+			rel:  rel,
+			file: f,
+			run: func(ctx context.Context) (string, error) {
+				uploaded, err := d.uploadFile(ctx, f)
+				if err == nil && len(d.fc.Aliases) > 0 {
+					err = d.copyToAliases(ctx, key, rel)
+				}
+				return uploaded, err
+			},
+		}) {
+			return
+		}
+	}
+
+	suffixes := maps.Keys(d.fc.Content)
+	sort.Strings(suffixes)
+	for _, suffix := range suffixes {
+		suffix := suffix
+		if !yield(uploadJob{
+			key:  d.contentKey(suffix),
+			rel:  suffix,
+			size: int64(len(d.fc.Content[suffix])),
+			run:  func(ctx context.Context) (string, error) { return d.uploadContent(ctx, suffix) },
+		}) {
+			return
+		}
+	}
+
+	for _, suffix := range d.fc.redirectOnlyKeys(rels) {
+		suffix := suffix
+		if !yield(uploadJob{
+			key: d.contentKey(suffix),
+			rel: suffix,
+			run: func(ctx context.Context) (string, error) { return d.uploadRedirect(ctx, suffix) },
+		}) {
+			return
+		}
+	}
+}
+
+// uploadFile uploads a single file, returning the key it was written to (or would be on a dry run).
+// The key is empty when the file did not need to be uploaded.
+func (d *deployment) uploadFile(ctx context.Context, f string) (string, error) {
+	// The key is always computed from the logical path, even when reading from where a symlink points to
+	src := f
+	if d.fc.FollowSymlinks {
+		resolved, err := filepath.EvalSymlinks(f)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve symlink %q, %v", f, err)
+		}
+		src = resolved
+	}
+
+	// Open the file for use
+	file, err := os.Open(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file %q, %v", f, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file %q, %v", f, err)
+	}
+
+	key := d.fc.objectKey(d.prefix, d.target.Cwd, f)
+
+	// body is what gets sent, which differs from the file when compressing
+	body, size := file, info.Size()
+	if d.fc.Compress {
+		compressed, err := compressFile(file, d.fc.TempDir)
+		if err != nil {
+			return "", fmt.Errorf("failed to compress file %q, %v", f, err)
+		}
+		defer removeTempFile(compressed)
+
+		compressedInfo, err := compressed.Stat()
+		if err != nil {
+			return "", fmt.Errorf("failed to stat compressed file %q, %v", f, err)
+		}
+
+		body, size = compressed, compressedInfo.Size()
+	}
+
+	var modTime time.Time
+	if d.fc.IfModifiedSince {
+		modTime = info.ModTime()
+	}
+
+	var xattrs map[string]string
+	if d.fc.ReadXattrs {
+		if xattrs, err = xattrMetadata(src, d.fc.Xattrs); err != nil {
+			return "", err
+		}
+	}
+
+	return d.send(ctx, key, relPath(d.target.Cwd, f), body, size, modTime, func(input *s3.PutObjectInput) {
+		if len(xattrs) > 0 {
+			if input.Metadata == nil {
+				input.Metadata = map[string]string{}
+			}
+			for name, value := range xattrs {
+				input.Metadata[name] = value
+			}
+		}
+		if d.fc.Compress {
+			input.ContentEncoding = aws.String("gzip")
+			if input.Metadata == nil {
+				input.Metadata = map[string]string{}
+			}
+			input.Metadata[uncompressedSizeMetadata] = strconv.FormatInt(info.Size(), 10)
+		}
+	})
+}
+
+// send uploads body to key, returning the key when it was written (or would be on a dry run).
+// rel is the path the object settings are matched against, customize adjusts the request before sending it.
+func (d *deployment) send(ctx context.Context, key, rel string, body io.ReadSeeker, size int64, modTime time.Time, customize func(*s3.PutObjectInput)) (string, error) {
+	input, err := d.putObjectInput(key, rel, body)
+	if err != nil {
+		return "", err
+	}
+
+	if customize != nil {
+		customize(input)
+	}
+
+	if err := d.receipt.record(d.receiptKey(key), body); err != nil {
+		return "", err
+	}
+
+	if d.runCtx.DryRun || d.fc.Incremental {
+		// only compare the metadata when it would be acted upon
+		var want *s3.PutObjectInput
+		if d.fc.SyncMetadata {
+			want = input
+		}
+
+		action, err := planUpload(ctx, d.client, d.bucket, key, body, size, modTime, want)
+		if err != nil {
+			return "", err
+		}
+
+		if d.fc.NoOverwrite && action != planCreate {
+			return "", fmt.Errorf("s3://%s/%s already exists and no_overwrite is set", d.bucket, key)
+		}
+
+		if d.runCtx.DryRun {
+			d.target.Infoln("%s s3://%s/%s (%d bytes)", action, d.bucket, key, size)
+			if action == planUnchanged {
+				return "", nil
+			}
+			return key, nil
+		} else if action == planUnchanged {
+			d.debugln(key, "unchanged, skipping %q", rel)
+			return "", nil
+		} else if action == planUpdateMetadata {
+			if err := d.updateMetadata(ctx, input); err != nil {
+				return "", err
+			}
+			d.debugln(key, "updated the metadata from %q", rel)
+			return key, nil
+		}
+	}
+
+	if d.fc.SendContentMD5 {
+		if err := setContentMD5(input, body, size, d.partSize); err != nil {
+			return "", fmt.Errorf("failed to read file %q, %v", rel, err)
+		}
+	}
+
+	uploadOpts := []func(*manager.Uploader){}
+	if d.fc.NoOverwrite {
+		// the plan already checked when incremental
+		if !d.fc.Incremental {
+			if err := d.checkNotExists(ctx, key); err != nil {
+				return "", err
+			}
+		}
+		uploadOpts = append(uploadOpts, ifNoneMatch)
+	}
+
+	// Use the uploader to upload the file
+	d.hooks.OnBeforeUpload(key, size)
+	start := time.Now()
+	var out *manager.UploadOutput
+	partSize := uploadPartSize(size, d.partSize)
+	if d.fc.ResumeUploads && size > d.partSize {
+		out, err = d.resumeMultipartUpload(ctx, input, body, size, partSize)
+	}
+	if out == nil && err == nil {
+		out, err = d.uploader.Upload(ctx, input, uploadOpts...)
+	}
+	d.hooks.OnAfterUpload(key, size, time.Since(start), err)
+	if err != nil {
+		d.abortMultipartUpload(key, err)
+		if d.fc.NoOverwrite && isPreconditionFailed(err) {
+			return "", fmt.Errorf("s3://%s/%s was created while uploading %q and no_overwrite is set", d.bucket, key, rel)
+		}
+		return "", fmt.Errorf("failed to upload file %q, %w", rel, err)
+	}
+
+	if d.fc.RecordManifest {
+		d.recordETag(key, aws.ToString(out.ETag))
+	}
+	if d.fc.CaptureVersions {
+		d.recordVersion(key, aws.ToString(out.VersionID))
+		d.debugln(key, "uploaded as version %s", aws.ToString(out.VersionID))
+	}
+
+	if d.fc.VerifyUploads {
+		if err := d.verifyUpload(ctx, key, body, size, partSize); err != nil {
+			return "", err
+		}
+	}
+
+	return key, nil
+}
+
+// debugln logs a line about a single object, prefixed with it so lines of concurrent uploads can be told apart
+func (d *deployment) debugln(key, format string, args ...interface{}) {
+	d.target.Debugln("s3://%s/%s: "+format, append([]interface{}{d.bucket, key}, args...)...)
+}
+
+// abortMultipartUpload cleans up the parts of a failed multipart upload. The uploader aborts them itself,
+// but uses the upload context to do so, which does not work once the deploy has been cancelled.
+// The parts are left in place when resuming uploads.
+func (d *deployment) abortMultipartUpload(key string, err error) {
+	if d.fc.ResumeUploads {
+		return
+	}
+
+	var multipartErr manager.MultiUploadFailure
+	if !errors.As(err, &multipartErr) || multipartErr.UploadID() == "" {
+		return
+	}
+
+	if _, abortErr := d.client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(d.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(multipartErr.UploadID()),
+	}); abortErr != nil {
+		d.debugln(key, "aborting multipart upload: %v", abortErr)
+	}
+}
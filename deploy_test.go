@@ -0,0 +1,268 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+)
+
+func TestDeployAndRemoveThroughFake(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	target := testTarget(t, fc, map[string]string{
+		"index.html":   "<html></html>",
+		"css/site.css": "body {}",
+	})
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	want := []string{"site/css/site.css", "site/index.html"}
+	if got := fake.keys("PutObject"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("uploaded %v, want %v", got, want)
+	}
+	if got := string(fake.object("site/index.html").body); got != "<html></html>" {
+		t.Errorf("site/index.html holds %q", got)
+	}
+	if got := fake.object("site/index.html").contentType; got != "text/html; charset=utf-8" {
+		t.Errorf("site/index.html has content type %q", got)
+	}
+
+	if err := runScript(t, fc, "remove", target, nil); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	if got := fake.count("DeleteObjects"); got != 1 {
+		t.Errorf("made %d DeleteObjects calls, want 1", got)
+	}
+	if got := fake.stored(); len(got) != 0 {
+		t.Errorf("objects left after remove: %v", got)
+	}
+}
+
+// failFast deploys a.bad, which fails while b.slow is uploading, followed by files that should never be dispatched
+func failFast(t *testing.T, drain bool) *fakeS3 {
+	t.Helper()
+
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	started, failing := make(chan struct{}), make(chan struct{})
+	fake.hook = func(ctx context.Context, op, key string) error {
+		switch {
+		case op != "PutObject":
+		case key == "site/a.bad":
+			<-started
+			close(failing)
+			return accessDeniedError()
+		case key == "site/b.slow":
+			close(started)
+			<-failing
+			if !drain {
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
+			}
+		}
+		return nil
+	}
+
+	fc := testConfig("site")
+	fc.FailFast = true
+	fc.DrainOnError = drain
+	fc.MaxParallel = new(int)
+	*fc.MaxParallel = 2
+	files := map[string]string{"a.bad": "a", "b.slow": "b"}
+	for _, name := range []string{"c", "d", "e", "f", "g"} {
+		files[name+".txt"] = name
+	}
+	target := testTarget(t, fc, files)
+
+	goroutines := runtime.NumGoroutine()
+	if err := runScript(t, fc, "deploy", target, nil); err == nil || !strings.Contains(err.Error(), "a.bad") {
+		t.Fatalf("got error %v, want the failure of a.bad", err)
+	}
+
+	// every worker is gone by the time deploy returns
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left running, %d before deploying", runtime.NumGoroutine(), goroutines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the upload that was waiting for a worker may have been picked up, the others were never dispatched
+	if n := fake.count("PutObject"); n > 3 {
+		t.Errorf("made %d uploads, want the dispatch to stop after the failure", n)
+	}
+	return fake
+}
+
+func TestFailFastCancelsRunningUploads(t *testing.T) {
+	fake := failFast(t, false)
+	if fake.object("site/b.slow") != nil {
+		t.Error("b.slow was uploaded despite the cancellation")
+	}
+}
+
+func TestFailFastDrainsRunningUploads(t *testing.T) {
+	fake := failFast(t, true)
+	if fake.object("site/b.slow") == nil {
+		t.Error("b.slow was not left to finish")
+	}
+}
+
+func TestEmptyOuts(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	target := testTarget(t, fc, nil)
+	if err := runScript(t, fc, "deploy", target, nil); err == nil || !strings.Contains(err.Error(), "matched no files") {
+		t.Errorf("got %v, want the empty srcs to fail the deploy", err)
+	}
+
+	fc.AllowEmpty = true
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Errorf("deploy with allow_empty: %v", err)
+	}
+	if n := fake.count("PutObject"); n != 0 {
+		t.Errorf("made %d uploads without files", n)
+	}
+}
+
+func TestManyFilesUseBoundedWorkers(t *testing.T) {
+	const files = 2000
+	contents := map[string]string{}
+	for i := 0; i < files; i++ {
+		contents[fmt.Sprintf("assets/%03d/%d.js", i%100, i)] = "x"
+	}
+
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	var mu sync.Mutex
+	running, maxRunning, maxGoroutines := 0, 0, 0
+	fake.hook = func(ctx context.Context, op, key string) error {
+		if op != "PutObject" {
+			return nil
+		}
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		if n := runtime.NumGoroutine(); n > maxGoroutines {
+			maxGoroutines = n
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}
+
+	fc := testConfig("site")
+	fc.MaxParallel = intPtr(4)
+	target := testTarget(t, fc, contents)
+
+	baseline := runtime.NumGoroutine()
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	if n := fake.count("PutObject"); n != files {
+		t.Errorf("uploaded %d files, want %d", n, files)
+	}
+	if maxRunning > 4 {
+		t.Errorf("%d uploads ran at once, want at most max_parallel", maxRunning)
+	}
+	// a goroutine per file would be thousands
+	if extra := maxGoroutines - baseline; extra > 50 {
+		t.Errorf("deploy started %d goroutines for %d files", extra, files)
+	}
+}
+
+func TestUploadJobsAreProducedOnDemand(t *testing.T) {
+	fc := testConfig("site")
+	fc.Content = map[string]string{"version.txt": "v1"}
+	target := testTarget(t, fc, map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"})
+	d := &deployment{fc: fc, target: target, prefix: "site"}
+
+	produced := []string{}
+	d.eachUploadJob(target.Outs, func(job uploadJob) bool {
+		produced = append(produced, job.key)
+		return len(produced) < 2
+	})
+	if len(produced) != 2 {
+		t.Errorf("produced %v, want no job after yield returned false", produced)
+	}
+}
+
+func TestFileAndPartConcurrencyAreIndependent(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	var mu sync.Mutex
+	partsRunning := map[string]int{}
+	maxParts, maxFiles := 0, 0
+	fake.hook = func(ctx context.Context, op, key string) error {
+		if op != "UploadPart" {
+			return nil
+		}
+		mu.Lock()
+		partsRunning[key]++
+		if partsRunning[key] > maxParts {
+			maxParts = partsRunning[key]
+		}
+		if len(partsRunning) > maxFiles {
+			maxFiles = len(partsRunning)
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		if partsRunning[key]--; partsRunning[key] == 0 {
+			delete(partsRunning, key)
+		}
+		mu.Unlock()
+		return nil
+	}
+
+	// 4 parts per file
+	content := strings.Repeat("x", 4*int(manager.DefaultUploadPartSize))
+	files := map[string]string{"a.bin": content, "b.bin": content, "c.bin": content}
+
+	fc := testConfig("site")
+	fc.MaxParallel = intPtr(2)
+	fc.PartConcurrency = intPtr(3)
+	target := testTarget(t, fc, files)
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	if n := fake.count("CompleteMultipartUpload"); n != 3 {
+		t.Fatalf("completed %d multipart uploads, want 3", n)
+	}
+	if maxParts != 3 {
+		t.Errorf("sent up to %d parts of a file at once, want part_concurrency", maxParts)
+	}
+	if maxFiles != 2 {
+		t.Errorf("uploaded up to %d files at once, want max_parallel", maxFiles)
+	}
+}
@@ -0,0 +1,124 @@
+package s3
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// S3Destination is a bucket the files are uploaded to, on top of the bucket of the target
+type S3Destination struct {
+	Bucket       string `mapstructure:"bucket" desc:"Bucket to upload to, can be interpolated"`
+	BucketPrefix string `mapstructure:"bucket_prefix" desc:"Key prefix in the bucket"`
+	Region       string `mapstructure:"region" desc:"Region of the bucket. Defaults to the region of the aws config"`
+	Endpoint     string `mapstructure:"endpoint" desc:"Endpoint of the bucket. Defaults to the endpoint of the target"`
+}
+
+// destination is a bucket resolved at runtime, along with the client to reach it
+type destination struct {
+	client s3API
+	// cfg the client was created from, for the clients that cannot go through it
+	cfg aws.Config
+	// pathStyle is whether the client addresses the bucket in the path
+	pathStyle bool
+	bucket    string
+	prefix    string
+}
+
+func (d destination) String() string {
+	return fmt.Sprintf("s3://%s/%s", d.bucket, d.prefix)
+}
+
+// destinations resolves the bucket of the target, when set, followed by the configured destinations.
+// The bucket can reference another target providing it, see readReferencedDestination.
+func (fc S3FileConfig) destinations(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) ([]destination, error) {
+	dests := []destination{}
+
+	if zen_targets.IsTargetReference(fc.Bucket) {
+		ref, err := readReferencedDestination(target)
+		if err != nil {
+			return nil, err
+		}
+		// the prefix of the target wins over the one of the reference
+		if fc.BucketPrefix != "" {
+			ref.BucketPrefix = fc.BucketPrefix
+		}
+
+		dest, err := loadDestination(target, runCtx, fc, ref)
+		if err != nil {
+			return nil, fmt.Errorf("bucket %s: %w", fc.Bucket, err)
+		}
+		dests = append(dests, dest)
+	} else if strings.TrimSpace(fc.Bucket) != "" {
+		cfg, bucket, prefix, err := loadAwsConfig(target, runCtx, fc)
+		if err != nil {
+			return nil, err
+		}
+		endpoint, err := resolveEndpoint(target, runCtx, fc, S3Destination{})
+		if err != nil {
+			return nil, err
+		}
+		resolved := fc.withPathStyle(endpoint)
+		dests = append(dests, destination{client: newS3API(cfg, resolved), cfg: cfg, pathStyle: *resolved.UsePathStyle, bucket: bucket, prefix: prefix})
+	}
+
+	for i, d := range fc.Destinations {
+		dest, err := loadDestination(target, runCtx, fc, d)
+		if err != nil {
+			return nil, fmt.Errorf("destination %d: %w", i, err)
+		}
+		dests = append(dests, dest)
+	}
+
+	for i := range dests {
+		// listing matches the prefix as is, so it has to look like the start of the keys it was joined into
+		dests[i].prefix = enforcePrefix(strings.TrimSuffix(collapseSlashes(dests[i].prefix), "/"))
+	}
+
+	return dests, nil
+}
+
+// loadDestination creates the client for a configured destination and resolves its bucket and prefix
+func loadDestination(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext, fc S3FileConfig, d S3Destination) (destination, error) {
+	cfg, err := newAwsConfig(target, runCtx, fc, d)
+	if err != nil {
+		return destination{}, fmt.Errorf("loading aws config: %w", err)
+	}
+
+	bucket, err := interpolateAtRuntime(target, runCtx, d.Bucket)
+	if err != nil {
+		return destination{}, fmt.Errorf("interpolating bucket name: %w", err)
+	} else if bucket == "" {
+		return destination{}, fmt.Errorf("bucket %q interpolated to an empty value", d.Bucket)
+	}
+
+	prefix, err := renderPlatform(target, d.BucketPrefix)
+	if err != nil {
+		return destination{}, err
+	}
+
+	prefix, err = interpolateAtRuntime(target, runCtx, prefix)
+	if err != nil {
+		return destination{}, fmt.Errorf("interpolating bucket key prefix: %w", err)
+	}
+
+	if fc.Tenant != "" {
+		tenant, err := interpolateAtRuntime(target, runCtx, fc.Tenant)
+		if err != nil {
+			return destination{}, fmt.Errorf("interpolating tenant: %w", err)
+		}
+		prefix = path.Join(prefix, tenant)
+	}
+
+	endpoint, err := resolveEndpoint(target, runCtx, fc, d)
+	if err != nil {
+		return destination{}, err
+	}
+	resolved := fc.withPathStyle(endpoint)
+
+	return destination{client: newS3API(cfg, resolved), cfg: cfg, pathStyle: *resolved.UsePathStyle, bucket: bucket, prefix: prefix}, nil
+}
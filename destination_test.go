@@ -0,0 +1,74 @@
+package s3
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestTenantInEveryKey(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.Tenant = "{TENANT}"
+	fc.RecordManifest = true
+	fc.Destinations = []S3Destination{{Bucket: "mirror", BucketPrefix: "copy"}}
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>", "css/site.css": "body {}"})
+	target.Env["TENANT"] = "acme"
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	keys := fake.keys("PutObject")
+	if len(keys) != 6 {
+		t.Fatalf("uploaded %v, want both files and the manifest to both destinations", keys)
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "site/acme/") && !strings.HasPrefix(key, "copy/acme/") {
+			t.Errorf("key %s is not under the tenant", key)
+		}
+	}
+}
+
+func TestDeployToEveryDestination(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.Destinations = []S3Destination{{Bucket: "mirror", BucketPrefix: "dr", Region: "eu-west-1"}}
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>", "app.js": "run()"})
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	got := []string{}
+	for _, in := range fake.inputs("PutObject") {
+		input := in.(*s3.PutObjectInput)
+		got = append(got, aws.ToString(input.Bucket)+"/"+aws.ToString(input.Key))
+	}
+	sort.Strings(got)
+	want := []string{"bucket/site/app.js", "bucket/site/index.html", "mirror/dr/app.js", "mirror/dr/index.html"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("uploaded %v, want %v", got, want)
+	}
+}
+
+func TestDestinationFailureNamesTheDestination(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	fake.fail("PutObject", "dr/app.js", accessDeniedError())
+
+	fc := testConfig("site")
+	fc.Destinations = []S3Destination{{Bucket: "mirror", BucketPrefix: "dr"}}
+	target := testTarget(t, fc, map[string]string{"app.js": "run()"})
+	err := runScript(t, fc, "deploy", target, nil)
+	if err == nil || !strings.Contains(err.Error(), "s3://mirror/dr") {
+		t.Errorf("got %v, want the failing destination to be named", err)
+	}
+}
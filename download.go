@@ -0,0 +1,131 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// download fetches the objects stored under the prefix into the download dir. Objects that are not newer
+// than their local copy are skipped, so pulling again only transfers what changed.
+func (fc S3FileConfig) download(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) error {
+	target.SetStatus("Downloading from s3 (%s)", target.Qn())
+
+	dests, err := fc.destinations(target, runCtx)
+	if err != nil {
+		return err
+	}
+	// the first destination is the bucket of the target, the others are copies of it
+	client, bucket, prefix := dests[0].client, dests[0].bucket, dests[0].prefix
+
+	dir := filepath.Join(target.Cwd, fc.DownloadDir)
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+	}
+	if p := strings.Trim(prefix, "/"); p != "" {
+		input.Prefix = aws.String(p + "/")
+	}
+
+	downloaded, skipped := 0, 0
+	paginator := s3.NewListObjectsV2Paginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return fmt.Errorf("listing objects in %s: %w", bucket, err)
+		}
+
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			rel := strings.TrimPrefix(key, aws.ToString(input.Prefix))
+			if rel == "" || strings.HasSuffix(rel, "/") {
+				// folder placeholders have nothing to download
+				continue
+			}
+
+			local := filepath.Join(dir, filepath.FromSlash(rel))
+			if !strings.HasPrefix(local, dir+string(filepath.Separator)) {
+				return fmt.Errorf("key %q points outside of the download dir", key)
+			}
+
+			if runCtx.DryRun {
+				target.Infoln("download s3://%s/%s to %s", bucket, key, local)
+				continue
+			}
+
+			fetched, err := downloadObject(context.TODO(), client, bucket, key, local)
+			if err != nil {
+				return err
+			} else if fetched {
+				downloaded++
+			} else {
+				skipped++
+			}
+		}
+	}
+
+	target.Infoln("downloaded %d objects, %d were unchanged", downloaded, skipped)
+	return nil
+}
+
+// downloadObject writes an object to local, unless local was modified after the object was.
+// It returns whether the object was fetched.
+func downloadObject(ctx context.Context, client s3API, bucket, key, local string) (bool, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if info, err := os.Stat(local); err == nil {
+		input.IfModifiedSince = aws.Time(info.ModTime())
+	}
+
+	out, err := client.GetObject(ctx, input)
+	if err != nil {
+		var respErr interface{ HTTPStatusCode() int }
+		if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotModified {
+			return false, nil
+		}
+		return false, fmt.Errorf("getting object %q: %w", key, err)
+	}
+	defer out.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(local), os.ModePerm); err != nil {
+		return false, fmt.Errorf("creating dir of %s: %w", local, err)
+	}
+
+	// write next to the destination and rename, so an interrupted download never leaves a partial file behind
+	tmp, err := os.CreateTemp(filepath.Dir(local), ".zen-download-*")
+	if err != nil {
+		return false, fmt.Errorf("creating temp file for %s: %w", local, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, out.Body); err != nil {
+		tmp.Close()
+		return false, fmt.Errorf("downloading object %q: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return false, fmt.Errorf("writing %s: %w", local, err)
+	}
+	if err := os.Rename(tmp.Name(), local); err != nil {
+		return false, fmt.Errorf("writing %s: %w", local, err)
+	}
+
+	// the local copy takes the modification time of the object, so the next download compares against it
+	if out.LastModified != nil {
+		if err := os.Chtimes(local, *out.LastModified, *out.LastModified); err != nil {
+			return false, fmt.Errorf("setting modification time of %s: %w", local, err)
+		}
+	}
+
+	return true, nil
+}
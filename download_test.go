@@ -0,0 +1,61 @@
+package s3
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestDownloadSkipsUnmodifiedObjects(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	fake.put("site/index.html", "<html></html>").lastModified = time.Now().Add(-time.Hour)
+	fake.put("site/css/site.css", "body {}").lastModified = time.Now().Add(-time.Hour)
+
+	fc := testConfig("site")
+	target := testTarget(t, fc, nil)
+	if err := runScript(t, fc, "download", target, nil); err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if !logged(target, "downloaded 2 objects, 0 were unchanged") {
+		t.Errorf("got logs %q, want both objects downloaded", target.Logs)
+	}
+
+	index := filepath.Join(target.Cwd, "download", "index.html")
+	if body, err := os.ReadFile(index); err != nil || string(body) != "<html></html>" {
+		t.Fatalf("downloaded %q, %v", body, err)
+	}
+
+	// edited locally since, which a 304 must leave alone
+	if err := os.WriteFile(index, []byte("local"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fake.put("site/css/site.css", "body { color: red }")
+
+	if err := runScript(t, fc, "download", target, nil); err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if !logged(target, "downloaded 1 objects, 1 were unchanged") {
+		t.Errorf("got logs %q, want only the modified object downloaded", target.Logs)
+	}
+	if body, _ := os.ReadFile(index); string(body) != "local" {
+		t.Errorf("the unmodified object overwrote the local file with %q", body)
+	}
+	if body, _ := os.ReadFile(filepath.Join(target.Cwd, "download", "css", "site.css")); string(body) != "body { color: red }" {
+		t.Errorf("the modified object was not downloaded, got %q", body)
+	}
+
+	gets := fake.inputs("GetObject")
+	if len(gets) != 4 {
+		t.Fatalf("made %d GetObject calls, want 4", len(gets))
+	}
+	for _, get := range gets[2:] {
+		if get.(*s3.GetObjectInput).IfModifiedSince == nil {
+			t.Errorf("downloading %s again was not conditional", aws.ToString(get.(*s3.GetObjectInput).Key))
+		}
+	}
+}
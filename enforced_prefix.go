@@ -0,0 +1,39 @@
+package s3
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+// enforcedPrefixEnv names the variable administrators set to confine every target to a key namespace.
+// It is read from the process env, so the config of a target cannot override it.
+const enforcedPrefixEnv = "ZEN_S3_ENFORCED_PREFIX"
+
+// enforcedPrefix returns the prefix every key is confined to, if any
+func enforcedPrefix() string {
+	return strings.Trim(os.Getenv(enforcedPrefixEnv), "/")
+}
+
+// enforcePrefix places prefix under the enforced prefix, unless it already is. Relative elements cannot
+// escape it, as the prefix is cleaned as if it was rooted.
+func enforcePrefix(prefix string) string {
+	enforced := enforcedPrefix()
+	if enforced == "" {
+		return prefix
+	}
+
+	cleaned := strings.TrimPrefix(path.Clean("/"+prefix), "/")
+	if cleaned == enforced || strings.HasPrefix(cleaned, enforced+"/") {
+		return cleaned
+	}
+
+	return path.Join(enforced, cleaned)
+}
+
+// underEnforcedPrefix checks whether key lies under the enforced prefix. Key templates and key casing
+// can move keys away from the prefix, so the final keys are checked as well.
+func underEnforcedPrefix(key string) bool {
+	enforced := enforcedPrefix()
+	return enforced == "" || strings.HasPrefix(key, enforced+"/")
+}
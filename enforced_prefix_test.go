@@ -0,0 +1,52 @@
+package s3
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnforcePrefix(t *testing.T) {
+	t.Setenv(enforcedPrefixEnv, "/teams/web/")
+
+	for prefix, want := range map[string]string{
+		"":                  "teams/web",
+		"site":              "teams/web/site",
+		"teams/web/site":    "teams/web/site",
+		"teams/website":     "teams/web/teams/website",
+		"../../etc":         "teams/web/etc",
+		"/teams/web/../ops": "teams/web/teams/ops",
+	} {
+		if got := enforcePrefix(prefix); got != want {
+			t.Errorf("enforcePrefix(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+}
+
+func TestEnforcedPrefixIsAlwaysApplied(t *testing.T) {
+	t.Setenv(enforcedPrefixEnv, "teams/web")
+
+	fc := testConfig("site")
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+	// the target env cannot override the one of the process
+	target.Env[enforcedPrefixEnv] = ""
+	fake := newFakeS3()
+	useFake(t, fake)
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if got, want := fake.stored(), []string{"teams/web/site/index.html"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored %v, want %v", got, want)
+	}
+
+	// a key template cannot move keys out of it either
+	fc.KeyTemplate = "/elsewhere/${basename}"
+	fake = newFakeS3()
+	useFake(t, fake)
+	if err := runScript(t, fc, "deploy", target, nil); err == nil || !strings.Contains(err.Error(), "outside of the enforced prefix") {
+		t.Errorf("got %v, want the key outside of the enforced prefix to be rejected", err)
+	}
+	if n := fake.count("PutObject"); n != 0 {
+		t.Errorf("uploaded %d files outside of the enforced prefix", n)
+	}
+}
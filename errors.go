@@ -0,0 +1,32 @@
+package s3
+
+import (
+	"errors"
+	"sync"
+)
+
+// multiError collects the errors of concurrent operations. The zero value is ready to use.
+type multiError struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// add records the errors that are not nil
+func (m *multiError) add(errs ...error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, err := range errs {
+		if err != nil {
+			m.errs = append(m.errs, err)
+		}
+	}
+}
+
+// err joins every recorded error, returning nil when there are none
+func (m *multiError) err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return errors.Join(m.errs...)
+}
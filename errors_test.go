@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMultiErrorCollectsConcurrentErrors(t *testing.T) {
+	var errs multiError
+	if err := errs.err(); err != nil {
+		t.Errorf("got %v without errors", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs.add(fmt.Errorf("error %d", i), nil)
+		}(i)
+	}
+	wg.Wait()
+
+	err := errs.err()
+	for i := 0; i < 100; i++ {
+		if !strings.Contains(err.Error()+"\n", fmt.Sprintf("error %d\n", i)) {
+			t.Errorf("error %d is missing from %v", i, err)
+		}
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 100 {
+		t.Errorf("joined %d errors, want 100 without the nil ones", n)
+	}
+}
+
+func TestDeployReportsEveryFailedUpload(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	for _, key := range []string{"site/a.txt", "site/b.txt", "site/c.txt"} {
+		fake.fail("PutObject", key, accessDeniedError())
+	}
+
+	fc := testConfig("site")
+	fc.MaxParallel = intPtr(4)
+	target := testTarget(t, fc, map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c", "d.txt": "d"})
+
+	err := runScript(t, fc, "deploy", target, nil)
+	if err == nil {
+		t.Fatal("deploy succeeded despite the failed uploads")
+	}
+	for _, file := range []string{"a.txt", "b.txt", "c.txt"} {
+		if !strings.Contains(err.Error(), fmt.Sprintf("%q", file)) {
+			t.Errorf("the failure of %s is missing from %v", file, err)
+		}
+	}
+	if strings.Contains(err.Error(), "d.txt") {
+		t.Errorf("got error %v, d.txt was uploaded", err)
+	}
+}
+
+func TestRemoveReportsEveryFailure(t *testing.T) {
+	fc := testConfig("site")
+	fc.SoftDeleteTag = "expire=true"
+	fake, target := deployFiles(t, fc, map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"})
+	fake.fail("PutObjectTagging", "site/a.txt", accessDeniedError())
+	fake.fail("PutObjectTagging", "site/c.txt", accessDeniedError())
+
+	err := runScript(t, fc, "remove", target, nil)
+	if err == nil {
+		t.Fatal("remove succeeded despite the failures")
+	}
+	if !strings.Contains(err.Error(), `"site/a.txt"`) || !strings.Contains(err.Error(), `"site/c.txt"`) || strings.Contains(err.Error(), "b.txt") {
+		t.Errorf("got error %v, want the failures of a.txt and c.txt", err)
+	}
+}
@@ -0,0 +1,813 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// testBucket is the bucket the test targets deploy to
+const testBucket = "bucket"
+
+// fakeObject is an object stored by fakeS3
+type fakeObject struct {
+	body         []byte
+	etag         string
+	versionID    string
+	lastModified time.Time
+	// the settings of the request that created the object
+	contentType        string
+	contentEncoding    string
+	contentDisposition string
+	cacheControl       string
+	redirect           string
+	metadata           map[string]string
+	storageClass       types.StorageClass
+	tags               []types.Tag
+}
+
+// fakeUpload is a multipart upload in progress
+type fakeUpload struct {
+	input     *s3.CreateMultipartUploadInput
+	initiated time.Time
+	parts     map[int32][]byte
+}
+
+// fakeCall is a call made to fakeS3
+type fakeCall struct {
+	op    string
+	key   string
+	input interface{}
+}
+
+// fakeS3 is an in-memory s3API, storing objects by bucket and key and recording every call made to it.
+// Errors are injected per operation and key with fail, or for every call with hook.
+type fakeS3 struct {
+	mu         sync.Mutex
+	objects    map[string]*fakeObject
+	uploads    map[string]*fakeUpload
+	calls      []fakeCall
+	errs       map[string]error
+	nextID     int
+	ownership  types.ObjectOwnership
+	versioning types.BucketVersioningStatus
+	bucketACL  []types.Grant
+
+	// hook is called before every call, outside of the lock, and fails it when returning an error
+	hook func(ctx context.Context, op, key string) error
+}
+
+var _ s3API = (*fakeS3)(nil)
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		objects: map[string]*fakeObject{},
+		uploads: map[string]*fakeUpload{},
+		errs:    map[string]error{},
+	}
+}
+
+// fail makes the calls of op on key return err, or of op on every key when key is empty
+func (f *fakeS3) fail(op, key string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.errs[op+" "+key] = err
+}
+
+// call records a call and returns the error injected for it, if any. Like the sdk, it fails once ctx is done.
+func (f *fakeS3) call(ctx context.Context, op, key string, input interface{}) error {
+	if f.hook != nil {
+		if err := f.hook(ctx, op, key); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, fakeCall{op: op, key: key, input: input})
+	if err, ok := f.errs[op+" "+key]; ok {
+		return err
+	}
+	return f.errs[op+" "]
+}
+
+// inputs returns the inputs of the calls made to op, in order
+func (f *fakeS3) inputs(op string) []interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	inputs := []interface{}{}
+	for _, c := range f.calls {
+		if c.op == op {
+			inputs = append(inputs, c.input)
+		}
+	}
+	return inputs
+}
+
+// keys returns the sorted keys of the calls made to op
+func (f *fakeS3) keys(op string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := []string{}
+	for _, c := range f.calls {
+		if c.op == op {
+			keys = append(keys, c.key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// putInput returns the input of the last PutObject call made for key, or nil
+func (f *fakeS3) putInput(key string) *s3.PutObjectInput {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := len(f.calls) - 1; i >= 0; i-- {
+		if c := f.calls[i]; c.op == "PutObject" && c.key == key {
+			return c.input.(*s3.PutObjectInput)
+		}
+	}
+	return nil
+}
+
+func (f *fakeS3) count(op string) int {
+	return len(f.inputs(op))
+}
+
+// put stores an object in the test bucket, as a previous deploy would have
+func (f *fakeS3) put(key, body string) *fakeObject {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	obj := &fakeObject{body: []byte(body), etag: md5Hex([]byte(body)), lastModified: time.Now()}
+	f.objects[testBucket+"/"+key] = obj
+	return obj
+}
+
+// object returns the object stored under key in the test bucket, nil when there is none
+func (f *fakeS3) object(key string) *fakeObject {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.objects[testBucket+"/"+key]
+}
+
+// stored returns the sorted keys of the objects in the test bucket
+func (f *fakeS3) stored() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := []string{}
+	for k := range f.objects {
+		if strings.HasPrefix(k, testBucket+"/") {
+			keys = append(keys, strings.TrimPrefix(k, testBucket+"/"))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (f *fakeS3) store(bucket, key string, obj *fakeObject) *fakeObject {
+	obj.lastModified = time.Now()
+	if f.versioning == types.BucketVersioningStatusEnabled {
+		f.nextID++
+		obj.versionID = "v" + strconv.Itoa(f.nextID)
+	}
+	f.objects[bucket+"/"+key] = obj
+	return obj
+}
+
+func md5Hex(b []byte) string {
+	sum := md5.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// responseError is an error with the given HTTP status, as returned by the sdk
+func responseError(status int, header http.Header, err error) error {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status, Header: header}},
+			Err:      err,
+		},
+	}
+}
+
+func notFoundError() error {
+	return responseError(http.StatusNotFound, nil, &types.NotFound{Message: aws.String("Not Found")})
+}
+
+func noSuchKeyError() error {
+	return responseError(http.StatusNotFound, nil, &types.NoSuchKey{Message: aws.String("The specified key does not exist.")})
+}
+
+func accessDeniedError() error {
+	return responseError(http.StatusForbidden, nil, &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"})
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	key := aws.ToString(params.Key)
+	if err := f.call(ctx, "PutObject", key, params); err != nil {
+		return nil, err
+	}
+
+	body := []byte{}
+	if params.Body != nil {
+		var err error
+		if body, err = io.ReadAll(params.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	obj := f.store(aws.ToString(params.Bucket), key, &fakeObject{
+		body:               body,
+		etag:               md5Hex(body),
+		contentType:        aws.ToString(params.ContentType),
+		contentEncoding:    aws.ToString(params.ContentEncoding),
+		contentDisposition: aws.ToString(params.ContentDisposition),
+		cacheControl:       aws.ToString(params.CacheControl),
+		redirect:           aws.ToString(params.WebsiteRedirectLocation),
+		metadata:           params.Metadata,
+		storageClass:       params.StorageClass,
+	})
+	return &s3.PutObjectOutput{ETag: aws.String(`"` + obj.etag + `"`), VersionId: nilIfEmpty(obj.versionID)}, nil
+}
+
+func (f *fakeS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	key := aws.ToString(params.Key)
+	if err := f.call(ctx, "HeadObject", key, params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	obj, ok := f.objects[aws.ToString(params.Bucket)+"/"+key]
+	if !ok {
+		return nil, notFoundError()
+	}
+	if params.IfModifiedSince != nil && !obj.lastModified.After(*params.IfModifiedSince) {
+		return nil, responseError(http.StatusNotModified, nil, fmt.Errorf("not modified"))
+	}
+
+	return &s3.HeadObjectOutput{
+		ContentLength:           int64(len(obj.body)),
+		ETag:                    aws.String(`"` + obj.etag + `"`),
+		LastModified:            aws.Time(obj.lastModified),
+		VersionId:               nilIfEmpty(obj.versionID),
+		ContentType:             nilIfEmpty(obj.contentType),
+		ContentEncoding:         nilIfEmpty(obj.contentEncoding),
+		ContentDisposition:      nilIfEmpty(obj.contentDisposition),
+		CacheControl:            nilIfEmpty(obj.cacheControl),
+		WebsiteRedirectLocation: nilIfEmpty(obj.redirect),
+		Metadata:                obj.metadata,
+		StorageClass:            obj.storageClass,
+	}, nil
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	key := aws.ToString(params.Key)
+	if err := f.call(ctx, "GetObject", key, params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	obj, ok := f.objects[aws.ToString(params.Bucket)+"/"+key]
+	if !ok {
+		return nil, noSuchKeyError()
+	}
+	if params.IfModifiedSince != nil && !obj.lastModified.After(*params.IfModifiedSince) {
+		return nil, responseError(http.StatusNotModified, nil, &smithy.GenericAPIError{Code: "NotModified"})
+	}
+
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(obj.body)),
+		ContentLength: int64(len(obj.body)),
+		ETag:          aws.String(`"` + obj.etag + `"`),
+		LastModified:  aws.Time(obj.lastModified),
+		ContentType:   nilIfEmpty(obj.contentType),
+		Metadata:      obj.metadata,
+	}, nil
+}
+
+// copySourceObject looks up the object a copy reads from, the caller holds the lock
+func (f *fakeS3) copySourceObject(source string) (*fakeObject, error) {
+	unescaped, err := url.PathUnescape(source)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, ok := f.objects[strings.TrimPrefix(unescaped, "/")]
+	if !ok {
+		return nil, noSuchKeyError()
+	}
+	return obj, nil
+}
+
+func (f *fakeS3) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	key := aws.ToString(params.Key)
+	if err := f.call(ctx, "CopyObject", key, params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	src, err := f.copySourceObject(aws.ToString(params.CopySource))
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *src
+	if params.MetadataDirective == types.MetadataDirectiveReplace {
+		copied.contentType = aws.ToString(params.ContentType)
+		copied.contentEncoding = aws.ToString(params.ContentEncoding)
+		copied.contentDisposition = aws.ToString(params.ContentDisposition)
+		copied.cacheControl = aws.ToString(params.CacheControl)
+		copied.redirect = aws.ToString(params.WebsiteRedirectLocation)
+		copied.metadata = params.Metadata
+	}
+	copied.storageClass = params.StorageClass
+
+	obj := f.store(aws.ToString(params.Bucket), key, &copied)
+	return &s3.CopyObjectOutput{
+		CopyObjectResult: &types.CopyObjectResult{ETag: aws.String(`"` + obj.etag + `"`)},
+		VersionId:        nilIfEmpty(obj.versionID),
+	}, nil
+}
+
+func (f *fakeS3) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	key := aws.ToString(params.Key)
+	if err := f.call(ctx, "DeleteObject", key, params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.objects, aws.ToString(params.Bucket)+"/"+key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// DeleteObjects reports the errors injected for DeleteObjects on a key as errors of that key, like S3 does
+func (f *fakeS3) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	if err := f.call(ctx, "DeleteObjects", "", params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := &s3.DeleteObjectsOutput{}
+	for _, id := range params.Delete.Objects {
+		key := aws.ToString(id.Key)
+		if err, ok := f.errs["DeleteObjects "+key]; ok {
+			code := "InternalError"
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) {
+				code = apiErr.ErrorCode()
+			}
+			out.Errors = append(out.Errors, types.Error{Key: id.Key, Code: aws.String(code), Message: aws.String(err.Error())})
+			continue
+		}
+
+		delete(f.objects, aws.ToString(params.Bucket)+"/"+key)
+		out.Deleted = append(out.Deleted, types.DeletedObject{Key: id.Key})
+	}
+	return out, nil
+}
+
+func (f *fakeS3) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	key := aws.ToString(params.Key)
+	if err := f.call(ctx, "GetObjectTagging", key, params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	obj, ok := f.objects[aws.ToString(params.Bucket)+"/"+key]
+	if !ok {
+		return nil, noSuchKeyError()
+	}
+	return &s3.GetObjectTaggingOutput{TagSet: obj.tags}, nil
+}
+
+func (f *fakeS3) PutObjectTagging(ctx context.Context, params *s3.PutObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error) {
+	key := aws.ToString(params.Key)
+	if err := f.call(ctx, "PutObjectTagging", key, params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	obj, ok := f.objects[aws.ToString(params.Bucket)+"/"+key]
+	if !ok {
+		return nil, noSuchKeyError()
+	}
+	obj.tags = params.Tagging.TagSet
+	return &s3.PutObjectTaggingOutput{}, nil
+}
+
+func (f *fakeS3) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if err := f.call(ctx, "HeadBucket", "", params); err != nil {
+		return nil, err
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (f *fakeS3) GetBucketAcl(ctx context.Context, params *s3.GetBucketAclInput, optFns ...func(*s3.Options)) (*s3.GetBucketAclOutput, error) {
+	if err := f.call(ctx, "GetBucketAcl", "", params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return &s3.GetBucketAclOutput{Grants: f.bucketACL}, nil
+}
+
+func (f *fakeS3) GetBucketOwnershipControls(ctx context.Context, params *s3.GetBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error) {
+	if err := f.call(ctx, "GetBucketOwnershipControls", "", params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.ownership == "" {
+		return nil, responseError(http.StatusNotFound, nil, &smithy.GenericAPIError{Code: "OwnershipControlsNotFoundError"})
+	}
+	return &s3.GetBucketOwnershipControlsOutput{OwnershipControls: &types.OwnershipControls{
+		Rules: []types.OwnershipControlsRule{{ObjectOwnership: f.ownership}},
+	}}, nil
+}
+
+func (f *fakeS3) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
+	if err := f.call(ctx, "GetBucketVersioning", "", params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return &s3.GetBucketVersioningOutput{Status: f.versioning}, nil
+}
+
+// ListObjectsV2 lists the objects in key order, using the last key of a page as its continuation token
+func (f *fakeS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if err := f.call(ctx, "ListObjectsV2", aws.ToString(params.Prefix), params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	maxKeys := 1000
+	if params.MaxKeys > 0 {
+		maxKeys = int(params.MaxKeys)
+	}
+
+	keys := []string{}
+	for k := range f.objects {
+		bucket, key, _ := strings.Cut(k, "/")
+		if bucket == aws.ToString(params.Bucket) && strings.HasPrefix(key, aws.ToString(params.Prefix)) && key > aws.ToString(params.ContinuationToken) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	out := &s3.ListObjectsV2Output{}
+	for _, key := range keys {
+		if len(out.Contents) == maxKeys {
+			out.IsTruncated = true
+			out.NextContinuationToken = out.Contents[len(out.Contents)-1].Key
+			break
+		}
+		obj := f.objects[aws.ToString(params.Bucket)+"/"+key]
+		out.Contents = append(out.Contents, types.Object{
+			Key:          aws.String(key),
+			Size:         int64(len(obj.body)),
+			ETag:         aws.String(`"` + obj.etag + `"`),
+			LastModified: aws.Time(obj.lastModified),
+		})
+	}
+	out.KeyCount = int32(len(out.Contents))
+	return out, nil
+}
+
+func (f *fakeS3) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	key := aws.ToString(params.Key)
+	if err := f.call(ctx, "CreateMultipartUpload", key, params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	id := "upload-" + strconv.Itoa(f.nextID)
+	f.uploads[id] = &fakeUpload{input: params, initiated: time.Now(), parts: map[int32][]byte{}}
+	return &s3.CreateMultipartUploadOutput{Bucket: params.Bucket, Key: params.Key, UploadId: aws.String(id)}, nil
+}
+
+func (f *fakeS3) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	key := aws.ToString(params.Key)
+	if err := f.call(ctx, "UploadPart", key, params); err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	upload, ok := f.uploads[aws.ToString(params.UploadId)]
+	if !ok {
+		return nil, responseError(http.StatusNotFound, nil, &types.NoSuchUpload{})
+	}
+	upload.parts[params.PartNumber] = body
+	return &s3.UploadPartOutput{ETag: aws.String(`"` + md5Hex(body) + `"`)}, nil
+}
+
+// UploadPartCopy copies the range of the source object in bytes=start-end form, honoring CopySourceIfMatch
+func (f *fakeS3) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	key := aws.ToString(params.Key)
+	if err := f.call(ctx, "UploadPartCopy", key, params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	upload, ok := f.uploads[aws.ToString(params.UploadId)]
+	if !ok {
+		return nil, responseError(http.StatusNotFound, nil, &types.NoSuchUpload{})
+	}
+	src, err := f.copySourceObject(aws.ToString(params.CopySource))
+	if err != nil {
+		return nil, err
+	}
+	if params.CopySourceIfMatch != nil && strings.Trim(*params.CopySourceIfMatch, `"`) != src.etag {
+		return nil, responseError(http.StatusPreconditionFailed, nil, &smithy.GenericAPIError{Code: "PreconditionFailed"})
+	}
+
+	var start, end int
+	if _, err := fmt.Sscanf(aws.ToString(params.CopySourceRange), "bytes=%d-%d", &start, &end); err != nil {
+		return nil, err
+	}
+	if start > end || end >= len(src.body) {
+		return nil, responseError(http.StatusRequestedRangeNotSatisfiable, nil, &smithy.GenericAPIError{Code: "InvalidRange"})
+	}
+
+	part := append([]byte{}, src.body[start:end+1]...)
+	upload.parts[params.PartNumber] = part
+	return &s3.UploadPartCopyOutput{CopyPartResult: &types.CopyPartResult{ETag: aws.String(`"` + md5Hex(part) + `"`)}}, nil
+}
+
+// startUpload leaves a multipart upload of key with the given parts behind, as an interrupted deploy would
+func (f *fakeS3) startUpload(key string, parts ...[]byte) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	id := "upload-" + strconv.Itoa(f.nextID)
+	upload := &fakeUpload{
+		input:     &s3.CreateMultipartUploadInput{Bucket: aws.String(testBucket), Key: aws.String(key)},
+		initiated: time.Now(),
+		parts:     map[int32][]byte{},
+	}
+	for i, part := range parts {
+		upload.parts[int32(i+1)] = part
+	}
+	f.uploads[id] = upload
+	return id
+}
+
+func (f *fakeS3) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	key := aws.ToString(params.Key)
+	if err := f.call(ctx, "CompleteMultipartUpload", key, params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	upload, ok := f.uploads[aws.ToString(params.UploadId)]
+	if !ok {
+		return nil, responseError(http.StatusNotFound, nil, &types.NoSuchUpload{})
+	}
+	delete(f.uploads, aws.ToString(params.UploadId))
+
+	body := []byte{}
+	digests := []byte{}
+	for _, part := range params.MultipartUpload.Parts {
+		data := upload.parts[part.PartNumber]
+		sum := md5.Sum(data)
+		body = append(body, data...)
+		digests = append(digests, sum[:]...)
+	}
+
+	obj := f.store(aws.ToString(params.Bucket), key, &fakeObject{
+		body:               body,
+		etag:               fmt.Sprintf("%s-%d", md5Hex(digests), len(params.MultipartUpload.Parts)),
+		contentType:        aws.ToString(upload.input.ContentType),
+		contentEncoding:    aws.ToString(upload.input.ContentEncoding),
+		contentDisposition: aws.ToString(upload.input.ContentDisposition),
+		cacheControl:       aws.ToString(upload.input.CacheControl),
+		metadata:           upload.input.Metadata,
+		storageClass:       upload.input.StorageClass,
+	})
+	return &s3.CompleteMultipartUploadOutput{Key: params.Key, ETag: aws.String(`"` + obj.etag + `"`), VersionId: nilIfEmpty(obj.versionID)}, nil
+}
+
+func (f *fakeS3) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	if err := f.call(ctx, "AbortMultipartUpload", aws.ToString(params.Key), params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.uploads, aws.ToString(params.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (f *fakeS3) ListMultipartUploads(ctx context.Context, params *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+	if err := f.call(ctx, "ListMultipartUploads", aws.ToString(params.Prefix), params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := &s3.ListMultipartUploadsOutput{}
+	for id, upload := range f.uploads {
+		if key := aws.ToString(upload.input.Key); strings.HasPrefix(key, aws.ToString(params.Prefix)) {
+			out.Uploads = append(out.Uploads, types.MultipartUpload{Key: aws.String(key), UploadId: aws.String(id), Initiated: aws.Time(upload.initiated)})
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeS3) ListParts(ctx context.Context, params *s3.ListPartsInput, optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error) {
+	if err := f.call(ctx, "ListParts", aws.ToString(params.Key), params); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	upload, ok := f.uploads[aws.ToString(params.UploadId)]
+	if !ok {
+		return nil, responseError(http.StatusNotFound, nil, &types.NoSuchUpload{})
+	}
+
+	out := &s3.ListPartsOutput{}
+	for number, data := range upload.parts {
+		out.Parts = append(out.Parts, types.Part{PartNumber: number, Size: int64(len(data)), ETag: aws.String(`"` + md5Hex(data) + `"`)})
+	}
+	sort.Slice(out.Parts, func(i, j int) bool { return out.Parts[i].PartNumber < out.Parts[j].PartNumber })
+	return out, nil
+}
+
+func nilIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+// useFake makes the scripts talk to f instead of S3 for the rest of the test
+func useFake(t *testing.T, f *fakeS3) {
+	t.Helper()
+
+	orig := newS3API
+	newS3API = func(cfg aws.Config, fc S3FileConfig) s3API { return f }
+	t.Cleanup(func() { newS3API = orig })
+}
+
+// testConfig is the config of a target deploying to the test bucket under prefix, without credentials
+func testConfig(prefix string) S3FileConfig {
+	fc := S3FileConfig{Bucket: testBucket, BucketPrefix: prefix, Anonymous: true, Region: "us-east-1"}
+	fc.Name = "site"
+	return fc
+}
+
+// testTarget writes files, by path relative to the target cwd, and returns a target with them as outs,
+// labelled with the bucket, prefix and tenant of fc the way GetTargets labels it
+func testTarget(t *testing.T, fc S3FileConfig, files map[string]string) *zen_targets.Target {
+	t.Helper()
+
+	cwd := t.TempDir()
+	outs := []string{}
+	for rel, content := range files {
+		f := filepath.Join(cwd, rel)
+		if err := os.MkdirAll(filepath.Dir(f), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		outs = append(outs, f)
+	}
+	sort.Strings(outs)
+
+	labels := []string{"zen_bucket=" + fc.Bucket, "zen_bucket_prefix=" + fc.BucketPrefix}
+	if fc.Tenant != "" {
+		labels = append(labels, "zen_tenant="+fc.Tenant)
+	}
+
+	return &zen_targets.Target{
+		Name:   fc.Name,
+		Cwd:    cwd,
+		Outs:   outs,
+		Env:    map[string]string{},
+		Labels: labels,
+	}
+}
+
+// deployFiles deploys files with fc to a new fake, failing the test if the deploy fails
+func deployFiles(t *testing.T, fc S3FileConfig, files map[string]string) (*fakeS3, *zen_targets.Target) {
+	t.Helper()
+
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	target := testTarget(t, fc, files)
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	return fake, target
+}
+
+// runScript runs a script of the target fc creates, going through GetTargets like zen does
+func runScript(t *testing.T, fc S3FileConfig, script string, target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) error {
+	t.Helper()
+
+	builders, err := fc.GetTargets(&zen_targets.TargetConfigContext{})
+	if err != nil {
+		return err
+	}
+	if runCtx == nil {
+		runCtx = &zen_targets.RuntimeContext{}
+	}
+
+	s, ok := builders[0].Scripts[script]
+	if !ok {
+		t.Fatalf("target has no %s script", script)
+	}
+	return s.Run(target, runCtx)
+}
+
+// logged reports whether the target logged a line containing text
+func logged(target *zen_targets.Target, text string) bool {
+	for _, line := range target.Logs {
+		if strings.Contains(line, text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package s3
+
+import (
+	"path/filepath"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// matchGlobValue returns the value of the most specific pattern in m matching the relative path.
+// Longer patterns are considered more specific, ties are broken alphabetically.
+func matchGlobValue(m map[string]string, rel string) (string, bool) {
+	var matched string
+	found := false
+
+	rel = filepath.ToSlash(rel)
+	for pattern := range m {
+		if ok, _ := doublestar.Match(pattern, rel); !ok {
+			continue
+		}
+
+		if !found || len(pattern) > len(matched) || (len(pattern) == len(matched) && pattern < matched) {
+			matched = pattern
+			found = true
+		}
+	}
+
+	return m[matched], found
+}
+
+// matchesAnyGlob checks whether the relative path matches one of the patterns
+func matchesAnyGlob(patterns []string, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range patterns {
+		if ok, _ := doublestar.Match(pattern, rel); ok {
+			return true
+		}
+	}
+
+	return false
+}
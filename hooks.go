@@ -0,0 +1,56 @@
+package s3
+
+import (
+	"time"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+// UploadOptions lets programs embedding this package observe every object the deploy and remove scripts
+// upload and delete, e.g. to export metrics. The callbacks are called concurrently, from the goroutines
+// doing the uploads and deletes.
+type UploadOptions struct {
+	// OnBeforeUpload is called right before a file starts uploading
+	OnBeforeUpload func(key string, size int64)
+	// OnAfterUpload is called once an upload is done, with how long it took and the error if it failed
+	OnAfterUpload func(key string, size int64, duration time.Duration, err error)
+	// OnDeleted is called once per object the remove script deleted, soft deleted, or failed to
+	OnDeleted func(ObjectEvent)
+}
+
+// withDefaults fills the callbacks that were not provided with ones logging to the target, or doing nothing
+func (opts *UploadOptions) withDefaults(target *zen_targets.Target) UploadOptions {
+	resolved := UploadOptions{}
+	if opts != nil {
+		resolved = *opts
+	}
+
+	if resolved.OnBeforeUpload == nil {
+		resolved.OnBeforeUpload = func(key string, size int64) {
+			target.Debugln("%s: uploading %d bytes", key, size)
+		}
+	}
+	if resolved.OnAfterUpload == nil {
+		resolved.OnAfterUpload = func(key string, size int64, duration time.Duration, err error) {
+			if err == nil {
+				target.Debugln("%s: uploaded %d bytes in %s", key, size, duration)
+			}
+		}
+	}
+
+	if resolved.OnDeleted == nil {
+		resolved.OnDeleted = func(ObjectEvent) {}
+	}
+
+	return resolved
+}
+
+// ObjectEvent describes the delete of a single object, once it is done
+type ObjectEvent struct {
+	Bucket string
+	Key    string
+	// Size is the size of the object listed for deletion, when known
+	Size     int64
+	Duration time.Duration
+	Err      error
+}
@@ -0,0 +1,148 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestObjectCallbacks(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	var mu sync.Mutex
+	uploaded := map[string]int64{}
+	deleted := []string{}
+
+	fc := testConfig("site")
+	fc.UploadOptions = &UploadOptions{
+		OnAfterUpload: func(key string, size int64, duration time.Duration, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, ok := uploaded[key]; ok {
+				t.Errorf("%s reported twice", key)
+			}
+			if err != nil || duration < 0 {
+				t.Errorf("%s: unexpected upload of %d bytes in %s, %v", key, size, duration, err)
+			}
+			uploaded[key] = size
+		},
+		OnDeleted: func(event ObjectEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			if event.Bucket != testBucket || event.Err != nil {
+				t.Errorf("unexpected delete event %+v", event)
+			}
+			deleted = append(deleted, event.Key)
+		},
+	}
+	target := testTarget(t, fc, map[string]string{
+		"index.html": "<html></html>",
+		"app.js":     "run()",
+		"style.css":  "body {}",
+	})
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	if want := map[string]int64{"site/index.html": 13, "site/app.js": 5, "site/style.css": 7}; !reflect.DeepEqual(uploaded, want) {
+		t.Errorf("got uploads %v, want %v", uploaded, want)
+	}
+
+	if err := runScript(t, fc, "remove", target, nil); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	sort.Strings(deleted)
+	if want := []string{"site/app.js", "site/index.html", "site/style.css"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("got delete events for %v, want %v", deleted, want)
+	}
+}
+
+func TestUploadCallbacks(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	fake.fail("PutObject", "site/bad.txt", accessDeniedError())
+
+	var mu sync.Mutex
+	before := map[string]int64{}
+	after := map[string]error{}
+
+	fc := testConfig("site")
+	fc.UploadOptions = &UploadOptions{
+		OnBeforeUpload: func(key string, size int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			before[key] = size
+		},
+		OnAfterUpload: func(key string, size int64, duration time.Duration, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if want, ok := before[key]; !ok || want != size {
+				t.Errorf("%s: after upload of %d bytes, before reported %d (%v)", key, size, want, ok)
+			}
+			if duration < 0 {
+				t.Errorf("%s: got duration %s", key, duration)
+			}
+			after[key] = err
+		},
+	}
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>", "bad.txt": "bad"})
+
+	if err := runScript(t, fc, "deploy", target, nil); err == nil {
+		t.Fatal("deploy succeeded despite the failed upload")
+	}
+
+	if want := map[string]int64{"site/index.html": 13, "site/bad.txt": 3}; !reflect.DeepEqual(before, want) {
+		t.Errorf("before upload got %v, want %v", before, want)
+	}
+	if err, ok := after["site/index.html"]; !ok || err != nil {
+		t.Errorf("after upload of site/index.html got %v (%v)", err, ok)
+	}
+	if err := after["site/bad.txt"]; err == nil {
+		t.Error("after upload of site/bad.txt got no error")
+	}
+}
+
+func TestDebugLogsOfConcurrentUploads(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	// both uploads are in flight at the same time
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	fake.hook = func(ctx context.Context, op, key string) error {
+		if op == "PutObject" {
+			arrived.Done()
+			arrived.Wait()
+		}
+		return nil
+	}
+
+	fc := testConfig("site")
+	fc.MaxParallel = intPtr(2)
+	target := testTarget(t, fc, map[string]string{"a.txt": "a", "b.txt": "bb"})
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	for _, line := range target.Logs {
+		if strings.Contains(line, "\n") {
+			t.Errorf("log line %q spans several lines", line)
+		}
+	}
+	for key, size := range map[string]int{"site/a.txt": 1, "site/b.txt": 2} {
+		if !logged(target, fmt.Sprintf("%s: uploading %d bytes", key, size)) {
+			t.Errorf("no log of the start of the upload of %s in %q", key, target.Logs)
+		}
+		if !logged(target, fmt.Sprintf("%s: uploaded %d bytes in ", key, size)) {
+			t.Errorf("no log of the end of the upload of %s in %q", key, target.Logs)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package s3
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// newHTTPClient builds the http client of the sdk. It goes through the proxy set in HTTPS_PROXY, unless
+// NO_PROXY excludes the endpoint, and trusts the certificates in ca_bundle on top of the system ones.
+func (fc S3FileConfig) newHTTPClient(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) (*awshttp.BuildableClient, error) {
+	var roots *x509.CertPool
+	if fc.CABundle != "" {
+		bundle, err := interpolateAtRuntime(target, runCtx, fc.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("interpolating ca bundle: %w", err)
+		}
+
+		if roots, err = loadCABundle(bundle); err != nil {
+			return nil, err
+		}
+	}
+
+	client := awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+		tr.Proxy = http.ProxyFromEnvironment
+		if roots != nil {
+			if tr.TLSClientConfig == nil {
+				tr.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+			}
+			tr.TLSClientConfig.RootCAs = roots
+		}
+	})
+
+	if fc.HTTPTimeout != "" {
+		timeout, _ := time.ParseDuration(fc.HTTPTimeout)
+		client = client.WithTimeout(timeout)
+	}
+
+	return client, nil
+}
+
+// loadCABundle returns the system certificate pool with the certificates of the pem encoded bundle added to it
+func loadCABundle(bundle string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("reading ca bundle: %w", err)
+	}
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("ca bundle %s has no pem encoded certificates", bundle)
+	}
+
+	return roots, nil
+}
@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+func TestCABundleIsTrusted(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "proxy-ca.pem")
+	if err := os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(fc S3FileConfig) error {
+		target := &zen_targets.Target{Name: "site", Cwd: dir, Env: map[string]string{"CERTS": dir}}
+		client, err := fc.newHTTPClient(target, &zen_targets.RuntimeContext{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client.GetTransport().TLSClientConfig == nil && fc.CABundle != "" {
+			t.Error("the transport has no tls config with a ca bundle")
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(testConfig("site")); err == nil {
+		t.Error("trusted the test server without its ca")
+	}
+
+	fc := testConfig("site")
+	fc.CABundle = "{CERTS}/proxy-ca.pem"
+	if err := get(fc); err != nil {
+		t.Errorf("request with the ca bundle: %v", err)
+	}
+}
+
+func TestCABundleErrors(t *testing.T) {
+	dir := t.TempDir()
+	notPem := filepath.Join(dir, "not.pem")
+	if err := os.WriteFile(notPem, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for bundle, want := range map[string]string{
+		filepath.Join(dir, "missing.pem"): "reading ca bundle",
+		notPem:                            "has no pem encoded certificates",
+	} {
+		if _, err := loadCABundle(bundle); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v, want %q", bundle, err, want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package s3
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+// keyTemplatePlaceholders are the ${...} placeholders of key_template filled in for every file
+var keyTemplatePlaceholders = []string{"prefix", "relpath", "basename", "dir"}
+
+func isKeyTemplatePlaceholder(name string) bool {
+	for _, placeholder := range keyTemplatePlaceholders {
+		if name == placeholder {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveKeyTemplate interpolates the variables referenced by the key template, e.g. ${VERSION}, leaving only
+// the per file placeholders to render
+func (fc S3FileConfig) resolveKeyTemplate(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) (string, error) {
+	if fc.KeyTemplate == "" {
+		return "", nil
+	}
+
+	// ${VAR} becomes {VAR}, the syntax of the interpolation
+	converted := os.Expand(fc.KeyTemplate, func(name string) string {
+		if isKeyTemplatePlaceholder(name) {
+			return "${" + name + "}"
+		}
+		return "{" + name + "}"
+	})
+
+	return interpolateAtRuntime(target, runCtx, converted)
+}
+
+// renderKeyTemplate computes the key of the file at rel, relative to the target directory, from the resolved template
+func renderKeyTemplate(tmpl, prefix, rel string) string {
+	rel = filepath.ToSlash(rel)
+	dir := path.Dir(rel)
+	if dir == "." {
+		dir = ""
+	}
+
+	values := map[string]string{
+		"prefix":   filepath.ToSlash(prefix),
+		"relpath":  rel,
+		"basename": path.Base(rel),
+		"dir":      dir,
+	}
+	key := os.Expand(tmpl, func(name string) string { return values[name] })
+
+	return strings.TrimPrefix(path.Clean("/"+key), "/")
+}
@@ -0,0 +1,70 @@
+package s3
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderKeyTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		tmpl, rel, want string
+	}{
+		{"${prefix}/${relpath}", "css/site.css", "site/css/site.css"},
+		{"releases/1.2.3/${basename}", "dist/linux/app.tar.gz", "releases/1.2.3/app.tar.gz"},
+		{"${dir}/latest/${basename}", "dist/app.zip", "dist/latest/app.zip"},
+		// the dir of a top level file is empty, without leaving a leading or double slash
+		{"${dir}/${basename}", "app.zip", "app.zip"},
+		{"${prefix}//${dir}/${basename}", "app.zip", "site/app.zip"},
+	} {
+		if got := renderKeyTemplate(tc.tmpl, "site", tc.rel); got != tc.want {
+			t.Errorf("%q of %q rendered %q, want %q", tc.tmpl, tc.rel, got, tc.want)
+		}
+	}
+}
+
+func TestKeyTemplate(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.KeyTemplate = "releases/${VERSION}/${basename}"
+	target := testTarget(t, fc, map[string]string{"dist/linux/app": "linux", "dist/darwin/app.pkg": "darwin"})
+	target.Env["VERSION"] = "1.2.3"
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if got, want := fake.keys("PutObject"), []string{"releases/1.2.3/app", "releases/1.2.3/app.pkg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("uploaded %v, want %v", got, want)
+	}
+}
+
+func TestKeyTemplateCollisions(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.KeyTemplate = "${prefix}/${basename}"
+	target := testTarget(t, fc, map[string]string{"linux/app": "linux", "darwin/app": "darwin"})
+
+	if err := runScript(t, fc, "deploy", target, nil); err == nil || !strings.Contains(err.Error(), "would both be uploaded to") {
+		t.Errorf("got %v, want the collision of both apps", err)
+	}
+	if n := fake.count("PutObject"); n != 0 {
+		t.Errorf("uploaded %d files despite the collision", n)
+	}
+}
+
+func TestKeyTemplateRendersEmptyKey(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.KeyTemplate = "${dir}"
+	target := testTarget(t, fc, map[string]string{"app.zip": "app"})
+
+	if err := runScript(t, fc, "deploy", target, nil); err == nil || !strings.Contains(err.Error(), "key_template renders an empty key") {
+		t.Errorf("got %v, want the empty key to be reported", err)
+	}
+}
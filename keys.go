@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// objectKey computes the key a file inside cwd is uploaded to.
+// Keys always use forward slashes, regardless of the separator of the OS, and never start with one.
+// The key template, when set, must already be resolved.
+func (fc S3FileConfig) objectKey(prefix, cwd, f string) string {
+	var key string
+	if fc.KeyTemplate != "" {
+		key = renderKeyTemplate(fc.KeyTemplate, prefix, relPath(cwd, f))
+	} else if fc.Flatten {
+		key = path.Join(filepath.ToSlash(prefix), filepath.Base(f))
+	} else {
+		key = path.Join(filepath.ToSlash(prefix), relPath(cwd, f))
+	}
+
+	return applyKeyCase(fc.KeyCase, collapseSlashes(key))
+}
+
+// relPath is the path of f relative to cwd, always slash separated, which is what the globs of the config match.
+// Files outside of cwd keep their whole path.
+func relPath(cwd, f string) string {
+	rel, err := filepath.Rel(cwd, f)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = f
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// collapseSlashes replaces runs of slashes with a single one, as a prefix ending in a slash
+// joined with a path starting with one would otherwise leave an empty segment in the key
+func collapseSlashes(key string) string {
+	for strings.Contains(key, "//") {
+		key = strings.ReplaceAll(key, "//", "/")
+	}
+
+	return key
+}
+
+const (
+	keyCasePreserve = "preserve"
+	keyCaseLower    = "lower"
+	keyCaseUpper    = "upper"
+)
+
+func applyKeyCase(policy, key string) string {
+	switch policy {
+	case keyCaseLower:
+		return strings.ToLower(key)
+	case keyCaseUpper:
+		return strings.ToUpper(key)
+	default:
+		return key
+	}
+}
+
+// checkKeyCollisions makes sure no two files end up under the same key, which can happen when flattening or
+// using a key template, and that the template renders a key for every file
+func (fc S3FileConfig) checkKeyCollisions(prefix, cwd string, files []string) error {
+	seen := map[string]string{}
+	for _, f := range files {
+		key := fc.objectKey(prefix, cwd, f)
+		if key == "" {
+			return fmt.Errorf("key_template renders an empty key for %q", f)
+		} else if other, ok := seen[key]; ok {
+			return fmt.Errorf("%q and %q would both be uploaded to %q", other, f, key)
+		}
+		seen[key] = f
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package s3
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	fc := testConfig("site")
+	fc.Flatten = true
+	fake, _ := deployFiles(t, fc, map[string]string{"css/site.css": "body {}", "js/app.js": "app()"})
+	if got, want := fake.keys("PutObject"), []string{"site/app.js", "site/site.css"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("flattened %v, want %v", got, want)
+	}
+
+	fake = newFakeS3()
+	useFake(t, fake)
+	target := testTarget(t, fc, map[string]string{"a/index.html": "a", "b/index.html": "b"})
+	if err := runScript(t, fc, "deploy", target, nil); err == nil || !strings.Contains(err.Error(), "would both be uploaded to") {
+		t.Errorf("got %v, want the collision of both index.html", err)
+	}
+	if n := fake.count("PutObject"); n != 0 {
+		t.Errorf("uploaded %d files despite the collision", n)
+	}
+}
+
+func TestWithoutFlattenPathsArePreserved(t *testing.T) {
+	fake, _ := deployFiles(t, testConfig("site"), map[string]string{"a/index.html": "a", "b/index.html": "b"})
+	if got, want := fake.keys("PutObject"), []string{"site/a/index.html", "site/b/index.html"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("uploaded %v, want %v", got, want)
+	}
+}
+
+func TestKeysUseForwardSlashes(t *testing.T) {
+	fc := testConfig("site")
+	cwd := filepath.Join(string(filepath.Separator)+"work", "site")
+	f := filepath.Join(cwd, "assets", "js", "app.js")
+	if got := fc.objectKey("site", cwd, f); got != "site/assets/js/app.js" {
+		t.Errorf("got key %q", got)
+	}
+	if got := fc.objectKey("", cwd, f); got != "assets/js/app.js" {
+		t.Errorf("got key %q without a prefix", got)
+	}
+}
+
+func TestRelPath(t *testing.T) {
+	cwd := filepath.Join(string(filepath.Separator)+"work", "site")
+	for f, want := range map[string]string{
+		filepath.Join(cwd, "index.html"):             "index.html",
+		filepath.Join(cwd, "assets", "js", "app.js"): "assets/js/app.js",
+		filepath.Join(cwd+"-other", "a.txt"):         filepath.ToSlash(filepath.Join(cwd+"-other", "a.txt")),
+	} {
+		if got := relPath(cwd, f); got != want {
+			t.Errorf("%s: got %q, want %q", f, got, want)
+		}
+	}
+
+	// the globs of the config match the relative path
+	fc := testConfig("site")
+	fc.ContentTypes = map[string]string{"assets/**/*.js": "text/javascript"}
+	fake, _ := deployFiles(t, fc, map[string]string{"assets/js/app.js": "app"})
+	if got := fake.object("site/assets/js/app.js").contentType; got != "text/javascript" {
+		t.Errorf("got content type %q, want the one of the glob", got)
+	}
+}
+
+func TestKeyCase(t *testing.T) {
+	tests := map[string][]string{
+		"":              {"Site/CSS/Site.css", "Site/Index.html"},
+		keyCasePreserve: {"Site/CSS/Site.css", "Site/Index.html"},
+		keyCaseLower:    {"site/css/site.css", "site/index.html"},
+		keyCaseUpper:    {"SITE/CSS/SITE.CSS", "SITE/INDEX.HTML"},
+	}
+
+	for policy, want := range tests {
+		t.Run(policy, func(t *testing.T) {
+			fc := testConfig("Site")
+			fc.KeyCase = policy
+			fake, _ := deployFiles(t, fc, map[string]string{"Index.html": "<html></html>", "CSS/Site.css": "body {}"})
+			if got := fake.keys("PutObject"); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+
+	fc := testConfig("site")
+	fc.KeyCase = "title"
+	if err := fc.validate(); err == nil || !strings.Contains(err.Error(), "key_case") {
+		t.Errorf("got %v, want an unknown key_case to be rejected", err)
+	}
+}
+
+func TestDuplicateSlashesAreCollapsed(t *testing.T) {
+	fc := testConfig("site")
+	cwd := filepath.Join(string(filepath.Separator)+"work", "site")
+	f := filepath.Join(cwd, "file.txt")
+	for prefix, want := range map[string]string{
+		"prefix/":      "prefix/file.txt",
+		"prefix//":     "prefix/file.txt",
+		"a//b/":        "a/b/file.txt",
+		"prefix///x//": "prefix/x/file.txt",
+	} {
+		if got := fc.objectKey(prefix, cwd, f); got != want {
+			t.Errorf("prefix %q: got key %q, want %q", prefix, got, want)
+		}
+	}
+
+	if got := collapseSlashes("prefix//file"); got != "prefix/file" {
+		t.Errorf("collapseSlashes(prefix//file) = %q", got)
+	}
+
+	fc = testConfig("site/")
+	fc.KeyTemplate = "${prefix}//${relpath}"
+	fake, _ := deployFiles(t, fc, map[string]string{"file.txt": "x"})
+	if got, want := fake.stored(), []string{"site/file.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// list prints the objects currently stored under the prefix
+func (fc S3FileConfig) list(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) error {
+	dests, err := fc.destinations(target, runCtx)
+	if err != nil {
+		return err
+	}
+
+	for _, dest := range dests {
+		if len(dests) > 1 {
+			target.Infoln("%s", dest)
+		}
+		if err := fc.listIn(target, dest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listIn prints the objects stored under the prefix of a single destination
+func (fc S3FileConfig) listIn(target *zen_targets.Target, dest destination) error {
+	client, bucket, prefix := dest.client, dest.bucket, dest.prefix
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+	}
+	if p := strings.Trim(prefix, "/"); p != "" {
+		input.Prefix = aws.String(p + "/")
+	}
+
+	listed := 0
+	paginator := s3.NewListObjectsV2Paginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return fmt.Errorf("listing objects in %s: %w", bucket, err)
+		}
+
+		for _, obj := range page.Contents {
+			if fc.ListMax > 0 && listed >= fc.ListMax {
+				target.Infoln("stopped after %d objects", fc.ListMax)
+				return nil
+			}
+
+			target.Infoln("%s\t%d\t%s\t%s", aws.ToString(obj.Key), obj.Size, aws.ToTime(obj.LastModified).Format(time.RFC3339), obj.StorageClass)
+			listed++
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package s3
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// listedKeys returns the keys printed by the list script
+func listedKeys(t *testing.T, fc S3FileConfig, fake *fakeS3) []string {
+	t.Helper()
+
+	target := testTarget(t, fc, nil)
+	if err := runScript(t, fc, "list", target, nil); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+
+	keys := []string{}
+	for _, line := range target.Logs {
+		if key, _, ok := strings.Cut(line, "\t"); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func TestListAllPages(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	for i := 0; i < 2500; i++ {
+		fake.put(fmt.Sprintf("site/%04d.txt", i), "x")
+	}
+	fake.put("other/0000.txt", "x")
+
+	keys := listedKeys(t, testConfig("site"), fake)
+	if len(keys) != 2500 {
+		t.Fatalf("listed %d keys, want 2500", len(keys))
+	}
+	for i, key := range keys {
+		if want := fmt.Sprintf("site/%04d.txt", i); key != want {
+			t.Fatalf("listed %s, want %s", key, want)
+		}
+	}
+	if n := fake.count("ListObjectsV2"); n != 3 {
+		t.Errorf("listed %d pages, want 3", n)
+	}
+}
+
+func TestListMax(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	for i := 0; i < 10; i++ {
+		fake.put(fmt.Sprintf("site/%d.txt", i), "x")
+	}
+
+	fc := testConfig("site")
+	fc.ListMax = 4
+	if keys := listedKeys(t, fc, fake); len(keys) != 4 {
+		t.Errorf("listed %v, want 4 keys", keys)
+	}
+}
@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// manifestName is the default object, under the prefix, recording the keys of the last deploy
+const manifestName = ".zen-manifest.json"
+
+// deployManifest records the exact keys a deploy owns, so they can be removed even if the key computation changed since
+type deployManifest struct {
+	Keys []string `json:"keys"`
+	// ETags of the keys uploaded by the deploy. Keys that were already up to date have none.
+	ETags map[string]string `json:"etags,omitempty"`
+	// Versions are the version ids the keys were uploaded as, when capturing them
+	Versions map[string]string `json:"versions,omitempty"`
+}
+
+func (fc S3FileConfig) manifestKey(prefix string) string {
+	return path.Join(prefix, fc.ManifestKey)
+}
+
+// writeManifest stores the keys owned by the target in the bucket
+func (d *deployment) writeManifest(ctx context.Context, keys []string) error {
+	d.versionsMu.Lock()
+	body, err := json.MarshalIndent(deployManifest{Keys: keys, ETags: d.etags, Versions: d.versions}, "", "  ")
+	d.versionsMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encoding deploy manifest: %w", err)
+	}
+
+	key := d.manifestKey
+	if _, err := d.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(d.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	}); err != nil {
+		return fmt.Errorf("uploading deploy manifest: %w", err)
+	}
+
+	d.target.Debugln("recorded %d keys in s3://%s/%s", len(keys), d.bucket, key)
+	return nil
+}
+
+// recordETag keeps the etag an object was uploaded with, for the manifest
+func (d *deployment) recordETag(key, etag string) {
+	if etag == "" {
+		return
+	}
+
+	d.versionsMu.Lock()
+	defer d.versionsMu.Unlock()
+	d.etags[key] = etag
+}
+
+// readManifest fetches the manifest recorded by the last deploy, returning nil when there is none
+func readManifest(ctx context.Context, client s3API, bucket, key string) (*deployManifest, error) {
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("downloading deploy manifest: %w", err)
+	}
+	defer out.Body.Close()
+
+	manifest := &deployManifest{}
+	if err := json.NewDecoder(out.Body).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("decoding deploy manifest %s: %w", key, err)
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,75 @@
+package s3
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRemoveUsesTheRecordedManifest(t *testing.T) {
+	fc := testConfig("site")
+	fc.RecordManifest = true
+	fc.KeyTemplate = "${prefix}/v1/${relpath}"
+	fake, target := deployFiles(t, fc, map[string]string{"index.html": "<html></html>", "css/site.css": "body {}"})
+	fake.put("site/unrelated.txt", "keep")
+
+	// the keys the target computes changed since the deploy
+	fc.KeyTemplate = "${prefix}/v2/${relpath}"
+	if err := runScript(t, fc, "remove", target, nil); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	if got, want := fake.stored(), []string{"site/unrelated.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("left %v, want %v", got, want)
+	}
+	if n := fake.count("ListObjectsV2"); n != 0 {
+		t.Errorf("listed the prefix %d times instead of reading the manifest", n)
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	fc := testConfig("site")
+	fc.RecordManifest = true
+	fc.ManifestKey = "meta/deployed.json"
+	fake, target := deployFiles(t, fc, map[string]string{"index.html": "<html></html>", "css/site.css": "body {}"})
+	fake.put("site/other-target.txt", "keep")
+
+	obj := fake.object("site/meta/deployed.json")
+	if obj == nil {
+		t.Fatalf("no manifest at the configured key, stored %v", fake.stored())
+	}
+	var manifest deployManifest
+	if err := json.Unmarshal(obj.body, &manifest); err != nil {
+		t.Fatalf("decoding manifest %s: %v", obj.body, err)
+	}
+	keys := append([]string{}, manifest.Keys...)
+	sort.Strings(keys)
+	if want := []string{"site/css/site.css", "site/index.html"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("manifest keys %v, want %v", keys, want)
+	}
+	for _, key := range manifest.Keys {
+		if etag, want := manifest.ETags[key], `"`+fake.object(key).etag+`"`; etag != want {
+			t.Errorf("manifest etag of %s is %q, want %q", key, etag, want)
+		}
+	}
+
+	// nothing is left locally to compute the keys from
+	for _, out := range target.Outs {
+		if err := os.Remove(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	target.Outs = nil
+
+	if err := runScript(t, fc, "remove", target, nil); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if got, want := fake.stored(), []string{"site/other-target.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("left %v, want %v", got, want)
+	}
+	if n := fake.count("ListObjectsV2"); n != 0 {
+		t.Errorf("listed the prefix %d times instead of reading the manifest", n)
+	}
+}
@@ -0,0 +1,38 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// setContentMD5 sets the Content-MD5 of single part uploads. The uploader streams the body, so computing it means
+// reading it an extra time before sending. Multipart uploads cannot carry a Content-MD5 for the whole object,
+// so they get a trailing checksum per part instead.
+func setContentMD5(input *s3.PutObjectInput, body io.ReadSeeker, size, partSize int64) error {
+	if size >= partSize {
+		if input.ChecksumAlgorithm == "" {
+			input.ChecksumAlgorithm = types.ChecksumAlgorithmCrc32
+		}
+		return nil
+	}
+
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	h := md5.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return fmt.Errorf("computing md5: %w", err)
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	input.ContentMD5 = aws.String(base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	return nil
+}
@@ -0,0 +1,37 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestSendContentMD5(t *testing.T) {
+	fc := testConfig("site")
+	fc.SendContentMD5 = true
+	fake, _ := deployFiles(t, fc, map[string]string{"a.txt": "hello"})
+
+	sum := md5.Sum([]byte("hello"))
+	if got, want := aws.ToString(fake.putInput("site/a.txt").ContentMD5), base64.StdEncoding.EncodeToString(sum[:]); got != want {
+		t.Errorf("got Content-MD5 %q, want %q", got, want)
+	}
+}
+
+func TestContentMD5FallsBackToChecksumsForMultipart(t *testing.T) {
+	body := bytes.NewReader(bytes.Repeat([]byte("x"), 16))
+	input := &s3.PutObjectInput{}
+	if err := setContentMD5(input, body, body.Size(), 8); err != nil {
+		t.Fatal(err)
+	}
+	if input.ContentMD5 != nil {
+		t.Errorf("set Content-MD5 %q on a multipart upload", aws.ToString(input.ContentMD5))
+	}
+	if input.ChecksumAlgorithm != types.ChecksumAlgorithmCrc32 {
+		t.Errorf("got checksum algorithm %q, want crc32", input.ChecksumAlgorithm)
+	}
+}
@@ -0,0 +1,100 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"golang.org/x/exp/maps"
+)
+
+// metadataDiffers checks whether the remote object carries different headers or metadata than the upload would set
+func metadataDiffers(head *s3.HeadObjectOutput, want *s3.PutObjectInput) bool {
+	return aws.ToString(head.ContentType) != aws.ToString(want.ContentType) ||
+		aws.ToString(head.ContentEncoding) != aws.ToString(want.ContentEncoding) ||
+		aws.ToString(head.ContentDisposition) != aws.ToString(want.ContentDisposition) ||
+		aws.ToString(head.CacheControl) != aws.ToString(want.CacheControl) ||
+		aws.ToString(head.WebsiteRedirectLocation) != aws.ToString(want.WebsiteRedirectLocation) ||
+		!maps.Equal(head.Metadata, want.Metadata)
+}
+
+// copySource is the url encoded bucket/key of an object, as expected by CopyObject
+func copySource(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return bucket + "/" + strings.Join(segments, "/")
+}
+
+// updateMetadata replaces the headers and metadata of an object whose content is already up to date,
+// copying it onto itself instead of uploading the body again. A copy resets whatever it is not given, so
+// every setting of the upload is passed along, and the tags are kept unless the upload sets its own.
+func (d *deployment) updateMetadata(ctx context.Context, input *s3.PutObjectInput) error {
+	tagging := types.TaggingDirectiveCopy
+	if input.Tagging != nil {
+		tagging = types.TaggingDirectiveReplace
+	}
+
+	_, err := d.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:                  input.Bucket,
+		Key:                     input.Key,
+		CopySource:              aws.String(copySource(aws.ToString(input.Bucket), aws.ToString(input.Key))),
+		MetadataDirective:       types.MetadataDirectiveReplace,
+		ContentType:             input.ContentType,
+		ContentEncoding:         input.ContentEncoding,
+		ContentDisposition:      input.ContentDisposition,
+		CacheControl:            input.CacheControl,
+		WebsiteRedirectLocation: input.WebsiteRedirectLocation,
+		Metadata:                input.Metadata,
+		ACL:                     input.ACL,
+		GrantRead:               input.GrantRead,
+		GrantReadACP:            input.GrantReadACP,
+		GrantWriteACP:           input.GrantWriteACP,
+		GrantFullControl:        input.GrantFullControl,
+		ServerSideEncryption:    input.ServerSideEncryption,
+		SSEKMSKeyId:             input.SSEKMSKeyId,
+		BucketKeyEnabled:        input.BucketKeyEnabled,
+		SSEKMSEncryptionContext: input.SSEKMSEncryptionContext,
+		StorageClass:            input.StorageClass,
+		// a copy is a new object, the retention and legal hold of the old one do not carry over
+		ObjectLockMode:            input.ObjectLockMode,
+		ObjectLockRetainUntilDate: input.ObjectLockRetainUntilDate,
+		ObjectLockLegalHoldStatus: input.ObjectLockLegalHoldStatus,
+		ChecksumAlgorithm:         input.ChecksumAlgorithm,
+		TaggingDirective:          tagging,
+		Tagging:                   input.Tagging,
+	})
+	if err != nil {
+		return fmt.Errorf("updating metadata of %q: %w", aws.ToString(input.Key), err)
+	}
+
+	return nil
+}
+
+// readXattrs reads the extended attributes of a file, it is the seam to stub them through
+var readXattrs = readFileXattrs
+
+// xattrMetadata reads the extended attributes of a file into object metadata. Only those in the user. namespace
+// are read by default, and the namespace is stripped from the metadata names.
+func xattrMetadata(path string, names []string) (map[string]string, error) {
+	xattrs, err := readXattrs(path, names)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{}
+	for name, value := range xattrs {
+		if len(names) == 0 && !strings.HasPrefix(name, "user.") {
+			continue
+		}
+		metadata[strings.TrimPrefix(name, "user.")] = value
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,111 @@
+package s3
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestUpdateMetadataKeepsObjectSettings(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.Incremental = true
+	fc.SyncMetadata = true
+	fc.StorageClass = string(types.StorageClassStandardIa)
+	fc.SSE = string(types.ServerSideEncryptionAwsKms)
+	fc.SSEKMSKeyID = "key"
+	fc.ObjectLockMode = string(types.ObjectLockModeGovernance)
+	fc.ObjectLockRetainUntil = time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+
+	// same content, outdated content type
+	fake.put("site/index.html", "<html></html>").contentType = "text/plain"
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	if n := fake.count("PutObject"); n != 0 {
+		t.Errorf("uploaded %d objects, want the metadata updated in place", n)
+	}
+	copies := fake.inputs("CopyObject")
+	if len(copies) != 1 {
+		t.Fatalf("made %d copies, want 1", len(copies))
+	}
+
+	input := copies[0].(*s3.CopyObjectInput)
+	if input.MetadataDirective != types.MetadataDirectiveReplace {
+		t.Errorf("metadata directive %q", input.MetadataDirective)
+	}
+	if aws.ToString(input.ContentType) != "text/html; charset=utf-8" {
+		t.Errorf("content type %q", aws.ToString(input.ContentType))
+	}
+	if input.StorageClass != types.StorageClassStandardIa {
+		t.Errorf("storage class %q, want %q", input.StorageClass, types.StorageClassStandardIa)
+	}
+	if input.ServerSideEncryption != types.ServerSideEncryptionAwsKms || aws.ToString(input.SSEKMSKeyId) != "key" {
+		t.Errorf("sse %q with key %q", input.ServerSideEncryption, aws.ToString(input.SSEKMSKeyId))
+	}
+	if input.ObjectLockMode != types.ObjectLockModeGovernance || input.ObjectLockRetainUntilDate == nil {
+		t.Errorf("object lock %q until %v", input.ObjectLockMode, input.ObjectLockRetainUntilDate)
+	}
+	if input.TaggingDirective != types.TaggingDirectiveCopy {
+		t.Errorf("tagging directive %q, want the tags kept", input.TaggingDirective)
+	}
+}
+
+// stubXattrs makes every file have xattrs, recording the names each read asked for
+func stubXattrs(t *testing.T, xattrs map[string]string) *[][]string {
+	reads := [][]string{}
+	orig := readXattrs
+	readXattrs = func(path string, names []string) (map[string]string, error) {
+		reads = append(reads, names)
+		if len(names) == 0 {
+			return xattrs, nil
+		}
+		found := map[string]string{}
+		for _, name := range names {
+			if value, ok := xattrs[name]; ok {
+				found[name] = value
+			}
+		}
+		return found, nil
+	}
+	t.Cleanup(func() { readXattrs = orig })
+	return &reads
+}
+
+func TestXattrMetadata(t *testing.T) {
+	stubXattrs(t, map[string]string{"user.origin": "ci", "user.checksum": "abc", "security.selinux": "label"})
+
+	fc := testConfig("site")
+	fc.ReadXattrs = true
+	fake, _ := deployFiles(t, fc, map[string]string{"a.txt": "a"})
+	if got, want := fake.putInput("site/a.txt").Metadata, map[string]string{"origin": "ci", "checksum": "abc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got metadata %v, want %v", got, want)
+	}
+
+	fc.Xattrs = []string{"user.origin", "security.selinux"}
+	fake, _ = deployFiles(t, fc, map[string]string{"a.txt": "a"})
+	if got, want := fake.putInput("site/a.txt").Metadata, map[string]string{"origin": "ci", "security.selinux": "label"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("with xattrs, got metadata %v, want %v", got, want)
+	}
+}
+
+func TestXattrsNotReadByDefault(t *testing.T) {
+	reads := stubXattrs(t, map[string]string{"user.origin": "ci"})
+
+	fake, _ := deployFiles(t, testConfig("site"), map[string]string{"a.txt": "a"})
+	if len(*reads) != 0 {
+		t.Errorf("read xattrs %d times without read_xattrs", len(*reads))
+	}
+	if got := fake.putInput("site/a.txt").Metadata; len(got) != 0 {
+		t.Errorf("got metadata %v", got)
+	}
+}
@@ -0,0 +1,139 @@
+package s3
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+// uploadableOuts returns the outs of the target that should be synced with the bucket
+func (fc S3FileConfig) uploadableOuts(target *zen_targets.Target) ([]string, error) {
+	outs := make([]string, 0, len(target.Outs))
+	for _, out := range target.Outs {
+		if isReferencedDestinationFile(target, out) {
+			continue
+		}
+
+		if !fc.FollowDirSymlinks {
+			linked, err := insideSymlinkedDir(target.Cwd, out)
+			if err != nil {
+				return nil, err
+			} else if linked {
+				target.Debugln("skipping %q, it is inside a symlinked directory", out)
+				continue
+			}
+		}
+
+		if fc.SkipBrokenSymlinks && isBrokenSymlink(out) {
+			target.Infoln("skipping %q, it is a broken symlink", out)
+			continue
+		}
+
+		if fc.ExcludeTools && isToolArtifact(target, out) {
+			target.Debugln("skipping %q, it belongs to a tool", out)
+			continue
+		}
+
+		outs = append(outs, out)
+	}
+
+	return outs, nil
+}
+
+// isBrokenSymlink checks whether f is a symlink to a file that does not exist
+func isBrokenSymlink(f string) bool {
+	info, err := os.Lstat(f)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+
+	_, err = os.Stat(f)
+	return os.IsNotExist(err)
+}
+
+// insideSymlinkedDir checks whether any directory between root and the file is a symlink
+func insideSymlinkedDir(root, f string) (bool, error) {
+	rel, err := filepath.Rel(root, filepath.Dir(f))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false, nil
+	}
+
+	dir := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, part)
+
+		info, err := os.Lstat(dir)
+		if err != nil {
+			return false, fmt.Errorf("checking %q: %w", dir, err)
+		} else if info.Mode()&os.ModeSymlink != 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// isToolArtifact checks whether the file is one of the tools of the target, or inside a tool directory
+func isToolArtifact(target *zen_targets.Target, f string) bool {
+	for _, tool := range target.Tools {
+		if tool == "" || zen_targets.IsTargetReference(tool) {
+			continue
+		}
+
+		toolPath := tool
+		if !filepath.IsAbs(toolPath) {
+			toolPath = filepath.Join(target.Cwd, toolPath)
+		}
+
+		if f == toolPath || strings.HasPrefix(f, toolPath+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkOutsExist makes sure every out is still on disk, so missing files are reported before uploading anything
+func checkOutsExist(outs []string) error {
+	missing, broken := []string{}, []string{}
+	for _, out := range outs {
+		if isBrokenSymlink(out) {
+			linked, _ := os.Readlink(out)
+			broken = append(broken, fmt.Sprintf("%s -> %s", out, linked))
+		} else if _, err := os.Stat(out); err != nil {
+			missing = append(missing, out)
+		}
+	}
+
+	if len(broken) > 0 {
+		return fmt.Errorf("%d files to deploy are broken symlinks, set skip_broken_symlinks to skip them: %s", len(broken), strings.Join(broken, ", "))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%d files to deploy do not exist: %s", len(missing), strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// checkOutsSize fails when any of the outs is larger than max bytes, listing all of them
+func checkOutsSize(outs []string, max int64) error {
+	tooLarge := []string{}
+	for _, out := range outs {
+		info, err := os.Stat(out)
+		if err != nil {
+			return err
+		}
+		if info.Size() > max {
+			tooLarge = append(tooLarge, fmt.Sprintf("%s (%d bytes)", out, info.Size()))
+		}
+	}
+
+	if len(tooLarge) > 0 {
+		return fmt.Errorf("%d files to deploy are larger than the max file size of %d bytes: %s", len(tooLarge), max, strings.Join(tooLarge, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package s3
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSymlinkedDirsSkippedByDefault(t *testing.T) {
+	fc := testConfig("site")
+	target := testTarget(t, fc, map[string]string{"real/a.txt": "a"})
+	if err := os.Symlink(filepath.Join(target.Cwd, "real"), filepath.Join(target.Cwd, "linked")); err != nil {
+		t.Fatal(err)
+	}
+	real, linked := filepath.Join(target.Cwd, "real", "a.txt"), filepath.Join(target.Cwd, "linked", "a.txt")
+	target.Outs = []string{real, linked}
+
+	outs, err := fc.uploadableOuts(target)
+	if err != nil {
+		t.Fatal(err)
+	} else if want := []string{real}; !reflect.DeepEqual(outs, want) {
+		t.Errorf("got %v, want %v", outs, want)
+	}
+
+	fc.FollowDirSymlinks = true
+	outs, err = fc.uploadableOuts(target)
+	if err != nil {
+		t.Fatal(err)
+	} else if want := []string{real, linked}; !reflect.DeepEqual(outs, want) {
+		t.Errorf("following symlinked dirs, got %v, want %v", outs, want)
+	}
+}
+
+func TestExcludeTools(t *testing.T) {
+	fc := testConfig("site")
+	fc.ExcludeTools = true
+	target := testTarget(t, fc, map[string]string{"index.html": "x", "tools/node/bin/node": "x", "protoc": "x"})
+	target.Tools = map[string]string{"node": "tools/node", "protoc": filepath.Join(target.Cwd, "protoc"), "go": "//tools:go"}
+
+	outs, err := fc.uploadableOuts(target)
+	if err != nil {
+		t.Fatal(err)
+	} else if want := []string{filepath.Join(target.Cwd, "index.html")}; !reflect.DeepEqual(outs, want) {
+		t.Errorf("got %v, want %v", outs, want)
+	}
+
+	fc.ExcludeTools = false
+	if outs, _ := fc.uploadableOuts(target); len(outs) != 3 {
+		t.Errorf("got %v, want the tools uploaded unless excluded", outs)
+	}
+}
+
+func TestMissingOutsFailBeforeUploading(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	target := testTarget(t, fc, map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"})
+	for _, name := range []string{"a.txt", "c.txt"} {
+		if err := os.Remove(filepath.Join(target.Cwd, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := runScript(t, fc, "deploy", target, nil)
+	if err == nil || !strings.Contains(err.Error(), "2 files to deploy do not exist") {
+		t.Fatalf("got %v, want both missing files reported", err)
+	}
+	for _, name := range []string{"a.txt", "c.txt"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error does not list %s: %v", name, err)
+		}
+	}
+	if n := fake.count("PutObject"); n != 0 {
+		t.Errorf("made %d uploads before noticing the missing files", n)
+	}
+}
+
+func TestFollowSymlinksKeepsTheLogicalKey(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	// generated outside of the target directory
+	generated := filepath.Join(t.TempDir(), "gen", "bundle-1a2b.js")
+	if err := os.MkdirAll(filepath.Dir(generated), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(generated, []byte("bundle"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fc := testConfig("site")
+	fc.FollowSymlinks = true
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+	link := filepath.Join(target.Cwd, "js", "app.js")
+	if err := os.MkdirAll(filepath.Dir(link), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(generated, link); err != nil {
+		t.Fatal(err)
+	}
+	target.Outs = append(target.Outs, link)
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if got, want := fake.stored(), []string{"site/index.html", "site/js/app.js"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("stored %v, want %v", got, want)
+	}
+	if body := string(fake.object("site/js/app.js").body); body != "bundle" {
+		t.Errorf("uploaded %q, want the content of the link target", body)
+	}
+}
+
+func TestBrokenSymlinks(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+	dangling := filepath.Join(target.Cwd, "latest.js")
+	if err := os.Symlink(filepath.Join(target.Cwd, "app-removed.js"), dangling); err != nil {
+		t.Fatal(err)
+	}
+	target.Outs = append(target.Outs, dangling)
+
+	err := runScript(t, fc, "deploy", target, nil)
+	if err == nil || !strings.Contains(err.Error(), "broken symlinks") || !strings.Contains(err.Error(), dangling+" -> ") {
+		t.Fatalf("got %v, want the dangling symlink reported", err)
+	}
+	if n := fake.count("PutObject"); n != 0 {
+		t.Errorf("uploaded %d files before failing", n)
+	}
+
+	fc.SkipBrokenSymlinks = true
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy skipping broken symlinks: %v", err)
+	}
+	if got, want := fake.stored(), []string{"site/index.html"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored %v, want %v", got, want)
+	}
+	if !logged(target, "it is a broken symlink") {
+		t.Errorf("the skipped symlink was not logged, got %q", target.Logs)
+	}
+}
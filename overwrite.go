@@ -0,0 +1,52 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// checkNotExists fails when an object is already stored under the key
+func (d *deployment) checkNotExists(ctx context.Context, key string) error {
+	_, err := d.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(d.bucket),
+		Key:    aws.String(key),
+	})
+	if err == nil {
+		return fmt.Errorf("s3://%s/%s already exists and no_overwrite is set", d.bucket, key)
+	}
+
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	return fmt.Errorf("checking whether s3://%s/%s exists: %w", d.bucket, key, err)
+}
+
+// ifNoneMatch makes the upload conditional on no object existing under the key, closing the window between
+// the existence check and the upload on endpoints supporting it. Only the requests creating the object carry it.
+func ifNoneMatch(u *manager.Uploader) {
+	u.ClientOptions = append(u.ClientOptions, func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			switch stack.ID() {
+			case "PutObject", "CompleteMultipartUpload":
+				return smithyhttp.AddHeaderValue("If-None-Match", "*")(stack)
+			}
+			return nil
+		})
+	})
+}
+
+// isPreconditionFailed checks whether a conditional request was rejected
+func isPreconditionFailed(err error) bool {
+	var respErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusPreconditionFailed
+}
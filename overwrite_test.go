@@ -0,0 +1,59 @@
+package s3
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestNoOverwrite(t *testing.T) {
+	for _, incremental := range []bool{false, true} {
+		fake := newFakeS3()
+		useFake(t, fake)
+		fake.put("site/app-1.0.0.tar.gz", "released")
+
+		fc := testConfig("site")
+		fc.NoOverwrite = true
+		fc.Incremental = incremental
+		target := testTarget(t, fc, map[string]string{"app-1.0.0.tar.gz": "rebuilt"})
+
+		err := runScript(t, fc, "deploy", target, nil)
+		if err == nil || !strings.Contains(err.Error(), "s3://bucket/site/app-1.0.0.tar.gz already exists and no_overwrite is set") {
+			t.Errorf("incremental %t: got %v, want the existing object to fail the deploy", incremental, err)
+		}
+		if n := fake.count("PutObject"); n != 0 {
+			t.Errorf("incremental %t: uploaded %d objects over the existing one", incremental, n)
+		}
+		if body := string(fake.object("site/app-1.0.0.tar.gz").body); body != "released" {
+			t.Errorf("incremental %t: the existing object was replaced with %q", incremental, body)
+		}
+	}
+}
+
+func TestNoOverwriteUploadsNewObjects(t *testing.T) {
+	fc := testConfig("site")
+	fc.NoOverwrite = true
+	fake, _ := deployFiles(t, fc, map[string]string{"app-1.0.1.tar.gz": "new"})
+
+	if obj := fake.object("site/app-1.0.1.tar.gz"); obj == nil || string(obj.body) != "new" {
+		t.Errorf("the new object was not uploaded, got %v", fake.stored())
+	}
+}
+
+func TestNoOverwriteObjectCreatedWhileUploading(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	// the conditional put is rejected, someone else created the object after the check
+	fake.fail("PutObject", "site/app.tar.gz", responseError(http.StatusPreconditionFailed, nil, &smithy.GenericAPIError{Code: "PreconditionFailed"}))
+
+	fc := testConfig("site")
+	fc.NoOverwrite = true
+	target := testTarget(t, fc, map[string]string{"app.tar.gz": "app"})
+
+	err := runScript(t, fc, "deploy", target, nil)
+	if err == nil || !strings.Contains(err.Error(), "was created while uploading") {
+		t.Errorf("got %v, want the race to be reported", err)
+	}
+}
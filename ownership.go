@@ -0,0 +1,35 @@
+package s3
+
+import (
+	"context"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// bucketOwnerEnforced checks whether the bucket has ACLs disabled, in which case uploads setting an ACL are rejected.
+// Failing to read the ownership controls (missing permissions, or no controls configured) is treated as not enforced.
+func bucketOwnerEnforced(ctx context.Context, target *zen_targets.Target, client s3API, bucket string) bool {
+	out, err := client.GetBucketOwnershipControls(ctx, &s3.GetBucketOwnershipControlsInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		target.Debugln("could not read ownership controls of %s: %v", bucket, err)
+		return false
+	}
+
+	if out.OwnershipControls == nil {
+		return false
+	}
+
+	for _, rule := range out.OwnershipControls.Rules {
+		if rule.ObjectOwnership == types.ObjectOwnershipBucketOwnerEnforced {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,54 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestBucketOwnerEnforcedDropsACLs(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		ownership types.ObjectOwnership
+		noDetect  bool
+		wantACL   bool
+	}{
+		{name: "enforced", ownership: types.ObjectOwnershipBucketOwnerEnforced},
+		{name: "preferred", ownership: types.ObjectOwnershipBucketOwnerPreferred, wantACL: true},
+		{name: "enforced without detection", ownership: types.ObjectOwnershipBucketOwnerEnforced, noDetect: true, wantACL: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3()
+			fake.ownership = tt.ownership
+			useFake(t, fake)
+
+			fc := testConfig("site")
+			fc.GrantRead = `uri="http://acs.amazonaws.com/groups/global/AllUsers"`
+			fc.Rules = []S3ObjectRule{{Match: "*.html", ACL: string(types.ObjectCannedACLPublicRead)}}
+			if tt.noDetect {
+				fc.DetectObjectOwnership = new(bool)
+			}
+			target := testTarget(t, fc, map[string]string{"index.html": "<html></html>", "app.js": "run()"})
+
+			if err := runScript(t, fc, "deploy", target, nil); err != nil {
+				t.Fatalf("deploy: %v", err)
+			}
+
+			puts := fake.inputs("PutObject")
+			if n := fake.count("GetBucketOwnershipControls"); tt.noDetect != (n == 0) {
+				t.Errorf("made %d ownership lookups", n)
+			}
+			if len(puts) != 2 {
+				t.Fatalf("made %d uploads, want 2", len(puts))
+			}
+			for _, put := range puts {
+				input := put.(*s3.PutObjectInput)
+				hasACL := input.ACL != "" || input.GrantRead != nil || input.GrantReadACP != nil || input.GrantWriteACP != nil || input.GrantFullControl != nil
+				if hasACL != tt.wantACL {
+					t.Errorf("%s sent acl %q and grant read %v", *input.Key, input.ACL, input.GrantRead)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// withPathStyle resolves use_path_style for the buckets behind endpoint, when it is not set explicitly.
+// Aws itself keeps path style, as it always did, while custom endpoints use it only when their host
+// cannot have the bucket name prepended to it, see hostsBuckets.
+func (fc S3FileConfig) withPathStyle(endpoint string) S3FileConfig {
+	if fc.UsePathStyle != nil {
+		return fc
+	}
+
+	pathStyle := true
+	if fc.UseAccelerate {
+		pathStyle = false
+	} else if endpoint != "" {
+		pathStyle = !hostsBuckets(endpoint)
+	}
+	fc.UsePathStyle = &pathStyle
+
+	return fc
+}
+
+// hostsBuckets checks whether the endpoint looks like it serves buckets as subdomains, e.g. https://cdn.example.com.
+// IP addresses, single label hosts like localhost or minio, internal domains and explicit ports, typical of
+// self hosted stores like https://minio.internal:9000, point to path style.
+func hostsBuckets(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+
+	host := u.Hostname()
+	switch {
+	case u.Port() != "":
+		return false
+	case net.ParseIP(host) != nil:
+		return false
+	case !strings.Contains(host, "."):
+		return false
+	case strings.HasSuffix(host, ".internal"), strings.HasSuffix(host, ".local"), strings.HasSuffix(host, ".localhost"):
+		return false
+	}
+
+	return true
+}
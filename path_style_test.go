@@ -0,0 +1,40 @@
+package s3
+
+import "testing"
+
+func TestPathStyleDetection(t *testing.T) {
+	for endpoint, want := range map[string]bool{
+		"":                            true,
+		"https://minio.internal:9000": true,
+		"http://localhost:9000":       true,
+		"http://minio":                true,
+		"http://10.0.0.12":            true,
+		"https://storage.local":       true,
+		"not a url":                   true,
+		"https://cdn.example.com":     false,
+		"https://s3.eu.example.org":   false,
+	} {
+		fc := testConfig("site").withPathStyle(endpoint)
+		if got := *fc.UsePathStyle; got != want {
+			t.Errorf("%q: got path style %v, want %v", endpoint, got, want)
+		}
+	}
+}
+
+func TestPathStyleOverride(t *testing.T) {
+	for _, want := range []bool{false, true} {
+		fc := testConfig("site")
+		fc.UsePathStyle = &want
+		for _, endpoint := range []string{"https://minio.internal:9000", "https://cdn.example.com"} {
+			if got := *fc.withPathStyle(endpoint).UsePathStyle; got != want {
+				t.Errorf("%q: got path style %v, want the configured %v", endpoint, got, want)
+			}
+		}
+	}
+
+	fc := testConfig("site")
+	fc.UseAccelerate = true
+	if *fc.withPathStyle("").UsePathStyle {
+		t.Error("got path style with acceleration")
+	}
+}
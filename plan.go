@@ -0,0 +1,116 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type planAction string
+
+const (
+	planCreate    planAction = "create"
+	planUpdate    planAction = "update"
+	planUnchanged planAction = "unchanged"
+	// planUpdateMetadata is an object with the same content, but different headers or metadata
+	planUpdateMetadata planAction = "update metadata"
+	planDelete         planAction = "delete"
+	// planSoftDelete is an object tagged for a lifecycle rule to expire it
+	planSoftDelete planAction = "soft delete"
+)
+
+// planUpload compares a local file with the remote object under key and returns what a deploy would do with it.
+// When modTime is set, the check is conditional: a remote object that was not modified since the local file was
+// (304 Not Modified) predates the local change, so it is considered outdated without hashing the file. Only a remote
+// object modified since then, which may already have the local content, is compared by ETag.
+// When want is set, an object with the same content but other headers or metadata only needs its metadata updated.
+func planUpload(ctx context.Context, client s3API, bucket, key string, body io.ReadSeeker, size int64, modTime time.Time, want *s3.PutObjectInput) (planAction, error) {
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if !modTime.IsZero() {
+		input.IfModifiedSince = aws.Time(modTime)
+	}
+
+	head, err := client.HeadObject(ctx, input)
+	if err != nil {
+		var notFound *types.NotFound
+		var respErr interface{ HTTPStatusCode() int }
+		if errors.As(err, &notFound) {
+			return planCreate, nil
+		} else if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotModified {
+			return planUpdate, nil
+		}
+		return "", fmt.Errorf("checking remote object %q: %w", key, err)
+	}
+
+	etag, err := localETag(body, size, uploadPartSize(size, manager.DefaultUploadPartSize))
+	if err != nil {
+		return "", fmt.Errorf("computing etag for %q: %w", key, err)
+	}
+
+	if strings.Trim(aws.ToString(head.ETag), `"`) == etag {
+		if want != nil && metadataDiffers(head, want) {
+			return planUpdateMetadata, nil
+		}
+		return planUnchanged, nil
+	}
+
+	return planUpdate, nil
+}
+
+// uploadPartSize is the part size the uploader actually uses for an object of size, which grows past partSize
+// when the object would otherwise take more than the maximum number of parts
+func uploadPartSize(size, partSize int64) int64 {
+	if size/partSize >= int64(manager.MaxUploadParts) {
+		return size/int64(manager.MaxUploadParts) + 1
+	}
+	return partSize
+}
+
+// localETag computes the ETag S3 would assign to body when uploaded by the manager with the given part size.
+// Single part uploads get the hex md5 of the content, multipart uploads the md5 of the part digests suffixed with the part count.
+func localETag(body io.ReadSeeker, size, partSize int64) (string, error) {
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	defer body.Seek(0, io.SeekStart)
+
+	if size < partSize {
+		h := md5.New()
+		if _, err := io.Copy(h, body); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	parts := 0
+	digests := md5.New()
+	for {
+		h := md5.New()
+		n, err := io.CopyN(h, body, partSize)
+		if n > 0 {
+			digests.Write(h.Sum(nil))
+			parts++
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(digests.Sum(nil)), parts), nil
+}
@@ -0,0 +1,123 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+func TestPlanUploadIfModifiedSince(t *testing.T) {
+	fake := newFakeS3()
+	fake.put("site/same.txt", "same")
+	fake.put("site/changed.txt", "old")
+
+	tests := []struct {
+		name    string
+		key     string
+		body    string
+		modTime time.Time
+		want    planAction
+	}{
+		{name: "not modified since", key: "site/changed.txt", body: "new", modTime: time.Now().Add(time.Hour), want: planUpdate},
+		{name: "not modified since with the same content", key: "site/same.txt", body: "same", modTime: time.Now().Add(time.Hour), want: planUpdate},
+		{name: "modified with other content", key: "site/changed.txt", body: "new", modTime: time.Now().Add(-time.Hour), want: planUpdate},
+		{name: "modified with the same content", key: "site/same.txt", body: "same", modTime: time.Now().Add(-time.Hour), want: planUnchanged},
+		{name: "missing", key: "site/new.txt", body: "new", modTime: time.Now(), want: planCreate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := bytes.NewReader([]byte(tt.body))
+			action, err := planUpload(context.Background(), fake, testBucket, tt.key, body, body.Size(), tt.modTime, nil)
+			if err != nil {
+				t.Fatal(err)
+			} else if action != tt.want {
+				t.Errorf("planned %q, want %q", action, tt.want)
+			}
+
+			inputs := fake.inputs("HeadObject")
+			input := inputs[len(inputs)-1].(*s3.HeadObjectInput)
+			if !aws.ToTime(input.IfModifiedSince).Equal(tt.modTime) {
+				t.Errorf("sent If-Modified-Since %v, want %v", input.IfModifiedSince, tt.modTime)
+			}
+		})
+	}
+}
+
+func TestDryRunPrintsThePlan(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	fake.put("site/same.txt", "same")
+	fake.put("site/changed.txt", "old")
+
+	fc := testConfig("site")
+	// logging is not safe for concurrent uploads
+	fc.MaxParallel = new(int)
+	*fc.MaxParallel = 1
+	target := testTarget(t, fc, map[string]string{"same.txt": "same", "changed.txt": "new", "new.txt": "brand new"})
+	runCtx := &zen_targets.RuntimeContext{DryRun: true}
+
+	if err := runScript(t, fc, "deploy", target, runCtx); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	for _, line := range []string{
+		"create s3://bucket/site/new.txt (9 bytes)",
+		"update s3://bucket/site/changed.txt (3 bytes)",
+		"unchanged s3://bucket/site/same.txt (4 bytes)",
+	} {
+		if !logged(target, line) {
+			t.Errorf("dry run did not print %q: %v", line, target.Logs)
+		}
+	}
+	if n := fake.count("PutObject"); n != 0 {
+		t.Errorf("dry run uploaded %d objects", n)
+	}
+
+	if err := runScript(t, fc, "remove", target, runCtx); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if !logged(target, "delete s3://bucket/site/same.txt (4 bytes)") {
+		t.Errorf("dry run did not print the deletion: %v", target.Logs)
+	}
+	if n := fake.count("DeleteObjects"); n != 0 {
+		t.Errorf("dry run deleted %d batches", n)
+	}
+	if got := fake.stored(); len(got) != 2 {
+		t.Errorf("dry run changed the bucket, now holding %v", got)
+	}
+}
+
+func TestDryRunCreatesNoUploader(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	created := 0
+	orig := newUploader
+	newUploader = func(client s3API, opts ...func(*manager.Uploader)) s3Uploader {
+		created++
+		return orig(client, opts...)
+	}
+	t.Cleanup(func() { newUploader = orig })
+
+	fc := testConfig("site")
+	target := testTarget(t, fc, map[string]string{"a.txt": "a"})
+	if err := runScript(t, fc, "deploy", target, &zen_targets.RuntimeContext{DryRun: true}); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if created != 0 {
+		t.Errorf("created %d uploaders on a dry run", created)
+	}
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if created != 1 {
+		t.Errorf("created %d uploaders on a deploy, want 1", created)
+	}
+}
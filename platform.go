@@ -0,0 +1,53 @@
+package s3
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"text/template"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+// platform is the data available to the {{.OS}} and {{.Arch}} placeholders of the bucket prefix
+type platform struct {
+	OS   string
+	Arch string
+}
+
+// targetPlatform reads the platform from the OS/ARCH (or GOOS/GOARCH) env vars of the target, defaulting to the host
+func targetPlatform(target *zen_targets.Target) platform {
+	p := platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+	for _, name := range []string{"GOOS", "OS"} {
+		if val := target.Env[name]; val != "" {
+			p.OS = val
+		}
+	}
+	for _, name := range []string{"GOARCH", "ARCH"} {
+		if val := target.Env[name]; val != "" {
+			p.Arch = val
+		}
+	}
+
+	return p
+}
+
+// renderPlatform fills in the {{.OS}} and {{.Arch}} placeholders of the prefix, so per platform artifacts
+// land under e.g. linux/amd64/. It runs before interpolation, which would otherwise take {.OS} as a variable.
+func renderPlatform(target *zen_targets.Target, prefix string) (string, error) {
+	if !strings.Contains(prefix, "{{") {
+		return prefix, nil
+	}
+
+	tmpl, err := template.New("prefix").Option("missingkey=error").Parse(prefix)
+	if err != nil {
+		return "", fmt.Errorf("parsing prefix template %q: %w", prefix, err)
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, targetPlatform(target)); err != nil {
+		return "", fmt.Errorf("rendering prefix template %q: %w", prefix, err)
+	}
+
+	return rendered.String(), nil
+}
@@ -0,0 +1,29 @@
+package s3
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlatformInKeys(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("releases/{{.OS}}/{{.Arch}}")
+	target := testTarget(t, fc, map[string]string{"bin/tool": "elf"})
+	target.Env["GOOS"] = "linux"
+	target.Env["GOARCH"] = "arm64"
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if got, want := fake.keys("PutObject"), []string{"releases/linux/arm64/bin/tool"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("uploaded %v, want %v", got, want)
+	}
+
+	// OS and ARCH take precedence over GOOS and GOARCH
+	target.Env["OS"] = "darwin"
+	target.Env["ARCH"] = "amd64"
+	if got := targetPlatform(target); got != (platform{OS: "darwin", Arch: "amd64"}) {
+		t.Errorf("got platform %+v", got)
+	}
+}
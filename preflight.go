@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+var (
+	// ErrBucketNotFound is returned by the pre-flight check when the bucket does not exist
+	ErrBucketNotFound = errors.New("bucket does not exist")
+	// ErrBucketAccessDenied is returned by the pre-flight check when the credentials are not allowed to access the bucket
+	ErrBucketAccessDenied = errors.New("access to the bucket is denied, check the credentials and the bucket policy")
+	// ErrBucketWrongRegion is returned by the pre-flight check when the bucket lives in another region than the configured one
+	ErrBucketWrongRegion = errors.New("bucket is in another region than the configured one")
+)
+
+// preflight checks the bucket is reachable before doing any work, so a misconfigured endpoint or missing access
+// is reported up front. It has its own timeout and retries, independent of the ones of the actual operations.
+func (fc S3FileConfig) preflight(target *zen_targets.Target, client s3API, bucket string) error {
+	// already validated in GetTargets
+	timeout, _ := time.ParseDuration(fc.PreflightTimeout)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+	defer cancel()
+
+	target.Debugln("checking access to %s", bucket)
+	if _, err := client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(bucket),
+	}, func(o *s3.Options) {
+		o.RetryMaxAttempts = *fc.PreflightRetries + 1
+	}); err != nil {
+		return fmt.Errorf("pre-flight check of bucket %s failed: %w", bucket, classifyBucketError(err))
+	}
+
+	return nil
+}
+
+// classifyBucketError wraps the error of a HeadBucket call with the matching Err* of the pre-flight check.
+// HeadBucket responses have no body, so the status code (and the region header S3 sets) is all there is to go by.
+func classifyBucketError(err error) error {
+	var respErr *awshttp.ResponseError
+	if !errors.As(err, &respErr) || respErr.Response == nil || respErr.Response.Response == nil {
+		return err
+	}
+
+	region := respErr.Response.Header.Get("x-amz-bucket-region")
+	switch status := respErr.HTTPStatusCode(); {
+	case status == http.StatusNotFound:
+		return fmt.Errorf("%w: %v", ErrBucketNotFound, err)
+	case status == http.StatusMovedPermanently, status == http.StatusBadRequest && region != "":
+		if region != "" {
+			return fmt.Errorf("%w, set region to %s: %v", ErrBucketWrongRegion, region, err)
+		}
+		return fmt.Errorf("%w: %v", ErrBucketWrongRegion, err)
+	case status == http.StatusForbidden:
+		return fmt.Errorf("%w: %v", ErrBucketAccessDenied, err)
+	}
+
+	return err
+}
@@ -0,0 +1,97 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyBucketError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"not found", notFoundError(), ErrBucketNotFound},
+		{"forbidden", accessDeniedError(), ErrBucketAccessDenied},
+		{"moved", responseError(http.StatusMovedPermanently, http.Header{"X-Amz-Bucket-Region": {"eu-west-1"}}, errors.New("moved")), ErrBucketWrongRegion},
+		{"bad request", responseError(http.StatusBadRequest, http.Header{"X-Amz-Bucket-Region": {"eu-west-1"}}, errors.New("bad request")), ErrBucketWrongRegion},
+	}
+	for _, tt := range tests {
+		if got := classifyBucketError(tt.err); !errors.Is(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyBucketErrorWithoutResponse(t *testing.T) {
+	err := withoutResponse(nil)
+	if got := classifyBucketError(err); got != err {
+		t.Errorf("got %v, want the error unchanged", got)
+	}
+}
+
+func TestPreflightTimeout(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	// a misconfigured endpoint that never answers
+	fake.hook = func(ctx context.Context, op, key string) error {
+		if op == "HeadBucket" {
+			<-ctx.Done()
+		}
+		return nil
+	}
+
+	fc := testConfig("site")
+	fc.Preflight = true
+	fc.PreflightTimeout = "50ms"
+	target := testTarget(t, fc, map[string]string{"a.txt": "a"})
+
+	start := time.Now()
+	err := runScript(t, fc, "deploy", target, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want the pre-flight to time out", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("pre-flight took %s", elapsed)
+	}
+	if n := fake.count("PutObject"); n != 0 {
+		t.Errorf("made %d uploads after the failed pre-flight", n)
+	}
+}
+
+func TestPreflightFailsBeforeAnyFileOperation(t *testing.T) {
+	for _, script := range []string{"deploy", "remove"} {
+		for name, tt := range map[string]struct {
+			err  error
+			want error
+		}{
+			"not found": {notFoundError(), ErrBucketNotFound},
+			"forbidden": {accessDeniedError(), ErrBucketAccessDenied},
+			"moved":     {responseError(http.StatusMovedPermanently, http.Header{"X-Amz-Bucket-Region": {"eu-west-1"}}, errors.New("moved")), ErrBucketWrongRegion},
+		} {
+			fake := newFakeS3()
+			useFake(t, fake)
+			fake.fail("HeadBucket", "", tt.err)
+
+			fc := testConfig("site")
+			fc.Preflight = true
+			target := testTarget(t, fc, map[string]string{"a.txt": "a"})
+
+			err := runScript(t, fc, script, target, nil)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("%s, %s: got %v, want %v", script, name, err, tt.want)
+			} else if tt.want == ErrBucketWrongRegion && !strings.Contains(err.Error(), "eu-west-1") {
+				t.Errorf("%s, %s: got %v, want the region of the bucket", script, name, err)
+			}
+			for _, call := range fake.calls {
+				if call.op != "HeadBucket" {
+					t.Errorf("%s, %s: called %s after the failed pre-flight", script, name, call.op)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// presign prints a time limited download url for each object of the target
+func (fc S3FileConfig) presign(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) error {
+	dests, err := fc.destinations(target, runCtx)
+	if err != nil {
+		return err
+	}
+	// the first destination is the bucket of the target, the others are copies of it
+	dest := dests[0]
+
+	// already validated in GetTargets
+	expiry, _ := time.ParseDuration(fc.PresignExpiry)
+
+	// the objects are the same ones remove would delete
+	objects, err := fc.removalObjects(context.TODO(), target, runCtx, dest)
+	if err != nil {
+		return err
+	}
+
+	// whoever follows the urls would have to send the expected owner header too, as it would be signed
+	unchecked := fc
+	unchecked.ExpectedBucketOwner = ""
+	unchecked.UsePathStyle = &dest.pathStyle
+	presigner := s3.NewPresignClient(newS3Client(dest.cfg, unchecked), s3.WithPresignExpires(expiry))
+	for _, obj := range objects {
+		req, err := presigner.PresignGetObject(context.TODO(), &s3.GetObjectInput{
+			Bucket: aws.String(dest.bucket),
+			Key:    aws.String(obj.key),
+		})
+		if err != nil {
+			return fmt.Errorf("presigning %q: %w", obj.key, err)
+		}
+
+		target.Infoln("%s\t%s", obj.key, req.URL)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package s3
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+func TestPresignUsesTheExpiry(t *testing.T) {
+	fc := testConfig("site")
+	fc.Anonymous = false
+	fc.AccessKeyID = "AKIDPRESIGN"
+	fc.SecretAccessKey = "secret"
+	fc.PresignExpiry = "15m"
+	fc.ExpectedBucketOwner = "123456789012"
+	_, target := deployFiles(t, fc, map[string]string{"index.html": "<html></html>", "app.tar.gz": "app"})
+
+	target.Logs = nil
+	if err := runScript(t, fc, "presign", target, nil); err != nil {
+		t.Fatalf("presign: %v", err)
+	}
+
+	urls := map[string]*url.URL{}
+	for _, line := range target.Logs {
+		key, raw, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		urls[key] = u
+	}
+	if len(urls) != 2 || urls["site/index.html"] == nil || urls["site/app.tar.gz"] == nil {
+		t.Fatalf("presigned %v, want both objects", urls)
+	}
+
+	for key, u := range urls {
+		query := u.Query()
+		if got := query.Get("X-Amz-Expires"); got != "900" {
+			t.Errorf("%s expires in %q seconds, want 900", key, got)
+		}
+		if !strings.HasPrefix(query.Get("X-Amz-Credential"), "AKIDPRESIGN/") {
+			t.Errorf("%s signed with %q", key, query.Get("X-Amz-Credential"))
+		}
+		if !strings.HasSuffix(u.Path, "/"+key) {
+			t.Errorf("%s presigned for %s", key, u.Path)
+		}
+		// the expected owner header would have to be sent by whoever follows the url
+		if strings.Contains(query.Get("X-Amz-SignedHeaders"), "x-amz-expected-bucket-owner") {
+			t.Errorf("%s signs the expected bucket owner header", key)
+		}
+	}
+}
+
+func TestPresignExpiryValidation(t *testing.T) {
+	for _, expiry := range []string{"0s", "-1h", "169h", "soon"} {
+		fc := testConfig("site")
+		fc.PresignExpiry = expiry
+		if _, err := fc.GetTargets(&zen_targets.TargetConfigContext{}); err == nil || !strings.Contains(err.Error(), "presign_expiry") {
+			t.Errorf("presign_expiry %q: got %v, want a configuration error", expiry, err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package s3
+
+import (
+	"context"
+
+	"github.com/aws/smithy-go/middleware"
+	"golang.org/x/time/rate"
+)
+
+// rateLimit makes every request, including retries, wait for a token of the limiter before being sent.
+// Buckets throttle on requests per second per prefix, which the upload concurrency alone does not bound.
+func rateLimit(limiter *rate.Limiter) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("RateLimit", func(
+			ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler,
+		) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			if err := limiter.Wait(ctx); err != nil {
+				return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+			}
+
+			return next.HandleFinalize(ctx, in)
+		}), middleware.After)
+	}
+}
+
+// newRateLimiter allows up to the given amount of requests per second, with bursts of at most one second worth of them
+func newRateLimiter(perSecond float64) *rate.Limiter {
+	burst := int(perSecond)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return rate.NewLimiter(rate.Limit(perSecond), burst)
+}
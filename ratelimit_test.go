@@ -0,0 +1,57 @@
+package s3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+func TestRateLimiterStaysUnderTheLimit(t *testing.T) {
+	limiter := newRateLimiter(5)
+
+	// a fake clock, asking for a token every 10ms over 10s
+	start := time.Unix(0, 0)
+	allowed := 0
+	for now := start; now.Before(start.Add(10 * time.Second)); now = now.Add(10 * time.Millisecond) {
+		if limiter.AllowN(now, 1) {
+			allowed++
+		}
+	}
+
+	// the initial burst of one second worth of requests, then 5 per second
+	if max := 5 + 5*10; allowed > max {
+		t.Errorf("allowed %d requests in 10s, want at most %d", allowed, max)
+	} else if allowed < 5*10 {
+		t.Errorf("allowed %d requests in 10s, want about 50", allowed)
+	}
+}
+
+func TestRateLimitMiddlewareWaits(t *testing.T) {
+	limiter := newRateLimiter(1)
+	stack := middleware.NewStack("test", smithyhttp.NewStackRequest)
+	if err := rateLimit(limiter)(stack); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := 0
+	handler := middleware.DecorateHandler(middleware.HandlerFunc(func(ctx context.Context, input interface{}) (interface{}, middleware.Metadata, error) {
+		sent++
+		return nil, middleware.Metadata{}, nil
+	}), stack)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := handler.Handle(ctx, struct{}{}); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	// the next token is a second away, past the deadline
+	if _, _, err := handler.Handle(ctx, struct{}{}); err == nil {
+		t.Error("second request was not held back by the limiter")
+	}
+	if sent != 1 {
+		t.Errorf("sent %d requests, want 1", sent)
+	}
+}
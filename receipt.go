@@ -0,0 +1,166 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// receiptName is the object, under the prefix, the receipt is uploaded as
+const receiptName = ".zen-receipt.json"
+
+// deployReceipt records the content of every object of a deploy. Keys are relative to the prefix, so
+// two deploys of the same content have the same checksum, whatever they were deployed under and when.
+type deployReceipt struct {
+	mu sync.Mutex
+
+	Timestamp string `json:"timestamp"`
+	Count     int    `json:"count"`
+	Bytes     int64  `json:"bytes"`
+	// Objects maps the keys to the sha256 of their content
+	Objects map[string]string `json:"objects"`
+	// Checksum is the sha256 of the sorted objects, see checksum
+	Checksum string `json:"checksum"`
+}
+
+func newDeployReceipt() *deployReceipt {
+	return &deployReceipt{Objects: map[string]string{}}
+}
+
+// record hashes the content of the object at key, rewinding body afterwards. It does nothing on a nil receipt.
+func (r *deployReceipt) record(key string, body io.ReadSeeker) error {
+	if r == nil {
+		return nil
+	}
+
+	h := sha256.New()
+	size, err := io.Copy(h, body)
+	if err != nil {
+		return fmt.Errorf("hashing %q: %w", key, err)
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewinding %q: %w", key, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the same object deployed to several destinations is only counted once
+	if _, ok := r.Objects[key]; !ok {
+		r.Count++
+		r.Bytes += size
+	}
+	r.Objects[key] = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// checksum is the sha256 of a "key sha256" line per object, sorted by key
+func (r *deployReceipt) checksum() string {
+	keys := maps.Keys(r.Objects)
+	slices.Sort(keys)
+
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s %s\n", key, r.Objects[key])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// encode seals the receipt with its checksum and the current time
+func (r *deployReceipt) encode() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Timestamp = now().UTC().Format(time.RFC3339)
+	r.Checksum = r.checksum()
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding deploy receipt: %w", err)
+	}
+
+	return data, nil
+}
+
+// write stores the receipt at p, relative to the target directory
+func (r *deployReceipt) write(cwd, p string, data []byte) error {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(cwd, p)
+	}
+
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		return fmt.Errorf("writing deploy receipt to %s: %w", p, err)
+	}
+
+	return nil
+}
+
+// upload stores the receipt under the prefix of the destination
+func (r *deployReceipt) upload(ctx context.Context, dest destination, data []byte) error {
+	key := path.Join(dest.prefix, receiptName)
+	if _, err := dest.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(dest.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}); err != nil {
+		return fmt.Errorf("uploading deploy receipt to %s: %w", dest, err)
+	}
+
+	return nil
+}
+
+// receiptKey is the key recorded in the receipt for an object of the deployment
+func (d *deployment) receiptKey(key string) string {
+	if d.prefix == "" {
+		return key
+	}
+
+	return strings.TrimPrefix(key, d.prefix+"/")
+}
+
+// storeReceipt writes the receipt locally and uploads it to every destination, as configured
+func (fc S3FileConfig) storeReceipt(ctx context.Context, target *zen_targets.Target, runCtx *zen_targets.RuntimeContext, dests []destination, receipt *deployReceipt) error {
+	data, err := receipt.encode()
+	if err != nil {
+		return err
+	}
+
+	if fc.Receipt != "" {
+		if err := receipt.write(target.Cwd, fc.Receipt, data); err != nil {
+			return err
+		}
+	}
+
+	if fc.UploadReceipt && !runCtx.DryRun {
+		uploads := make([]func(ctx context.Context) error, 0, len(dests))
+		for _, dest := range dests {
+			dest := dest
+			uploads = append(uploads, func(ctx context.Context) error { return receipt.upload(ctx, dest, data) })
+		}
+		if err := uploadAuxiliary(ctx, uploads...); err != nil {
+			return err
+		}
+	}
+
+	target.Debugln("deploy receipt checksum: %s", receipt.Checksum)
+	return nil
+}
@@ -0,0 +1,88 @@
+package s3
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReceiptIsDeterministic(t *testing.T) {
+	fixedClock(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	files := map[string]string{"index.html": "<html></html>", "css/site.css": "body {}", "js/app.js": "app()"}
+
+	receipts := [][]byte{}
+	for _, prefix := range []string{"site", "staging/site"} {
+		fc := testConfig(prefix)
+		fc.Receipt = "receipt.json"
+		fc.UploadReceipt = true
+		fake, target := deployFiles(t, fc, files)
+
+		local, err := os.ReadFile(filepath.Join(target.Cwd, "receipt.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		uploaded := fake.object(prefix + "/" + receiptName)
+		if uploaded == nil || string(uploaded.body) != string(local) {
+			t.Errorf("%s: the uploaded receipt does not match the local one", prefix)
+		}
+
+		var receipt deployReceipt
+		if err := json.Unmarshal(local, &receipt); err != nil {
+			t.Fatalf("decoding %s: %v", local, err)
+		}
+		want := map[string]string{}
+		for _, key := range fake.stored() {
+			if strings.HasSuffix(key, receiptName) {
+				continue
+			}
+			sum := sha256.Sum256(fake.object(key).body)
+			want[strings.TrimPrefix(key, prefix+"/")] = hex.EncodeToString(sum[:])
+		}
+		if !reflect.DeepEqual(receipt.Objects, want) {
+			t.Errorf("%s: receipt objects %v, want %v", prefix, receipt.Objects, want)
+		}
+		if receipt.Count != 3 || receipt.Bytes != int64(len("<html></html>")+len("body {}")+len("app()")) {
+			t.Errorf("%s: receipt counts %d objects of %d bytes", prefix, receipt.Count, receipt.Bytes)
+		}
+		if receipt.Timestamp != "2024-05-01T12:00:00Z" {
+			t.Errorf("%s: receipt timestamp %q", prefix, receipt.Timestamp)
+		}
+
+		receipts = append(receipts, local)
+	}
+
+	// the same content deployed under another prefix gets the same receipt
+	if string(receipts[0]) != string(receipts[1]) {
+		t.Errorf("receipts differ:\n%s\n%s", receipts[0], receipts[1])
+	}
+}
+
+func TestReceiptChecksumChangesWithContent(t *testing.T) {
+	checksum := func(content string) string {
+		fc := testConfig("site")
+		fc.Receipt = "receipt.json"
+		_, target := deployFiles(t, fc, map[string]string{"index.html": content})
+		data, err := os.ReadFile(filepath.Join(target.Cwd, "receipt.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var receipt deployReceipt
+		if err := json.Unmarshal(data, &receipt); err != nil {
+			t.Fatal(err)
+		}
+		return receipt.Checksum
+	}
+
+	if a, b := checksum("v1"), checksum("v1"); a != b {
+		t.Errorf("the same content got checksums %s and %s", a, b)
+	}
+	if a, b := checksum("v1"), checksum("v2"); a == b {
+		t.Error("different content got the same checksum")
+	}
+}
@@ -0,0 +1,48 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// validateRedirect checks the redirect target is one S3 accepts, which is either a path or an absolute url
+func validateRedirect(location string) error {
+	if !strings.HasPrefix(location, "/") && !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return fmt.Errorf("redirect location must start with /, http:// or https://, got %q", location)
+	}
+
+	return nil
+}
+
+// redirectOnlyKeys returns the literal redirect entries that do not match any file or inline content.
+// They are uploaded as empty objects that only carry the redirect.
+func (fc S3FileConfig) redirectOnlyKeys(rels []string) []string {
+	existing := map[string]bool{}
+	for _, rel := range rels {
+		existing[rel] = true
+	}
+	for suffix := range fc.Content {
+		existing[suffix] = true
+	}
+
+	keys := []string{}
+	for pattern := range fc.Redirects {
+		// globs only apply to the objects being uploaded
+		if !strings.ContainsAny(pattern, "*?[{") && !existing[pattern] {
+			keys = append(keys, pattern)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// uploadRedirect uploads an empty object redirecting to the location configured for the key suffix
+func (d *deployment) uploadRedirect(ctx context.Context, suffix string) (string, error) {
+	body := bytes.NewReader(nil)
+	return d.send(ctx, d.contentKey(suffix), suffix, body, 0, time.Time{}, nil)
+}
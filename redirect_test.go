@@ -0,0 +1,38 @@
+package s3
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestRedirects(t *testing.T) {
+	fc := testConfig("site")
+	fc.Redirects = map[string]string{
+		"old/path.html": "/new/path.html",
+		"docs/*.html":   "https://docs.example.com/",
+	}
+	fake, _ := deployFiles(t, fc, map[string]string{"docs/intro.html": "<html></html>", "index.html": "<html></html>"})
+
+	for key, want := range map[string]string{
+		"site/old/path.html":   "/new/path.html",
+		"site/docs/intro.html": "https://docs.example.com/",
+		"site/index.html":      "",
+	} {
+		input := fake.putInput(key)
+		if input == nil {
+			t.Errorf("%s was not uploaded", key)
+		} else if got := aws.ToString(input.WebsiteRedirectLocation); got != want {
+			t.Errorf("%s got redirect location %q, want %q", key, got, want)
+		}
+	}
+	if obj := fake.object("site/old/path.html"); obj == nil || len(obj.body) != 0 {
+		t.Error("the redirect matching no file is not an empty object")
+	}
+
+	fc.Redirects = map[string]string{"old.html": "new.html"}
+	if err := fc.validate(); err == nil || !strings.Contains(err.Error(), "redirect location") {
+		t.Errorf("got %v, want a relative location to be rejected", err)
+	}
+}
@@ -0,0 +1,85 @@
+package s3
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+// destinationSrc is the srcs group the outs of the target referenced by bucket are pulled into
+const destinationSrc = "_destination"
+
+// readReferencedDestination reads the destination provided by the target referenced by bucket. That target outputs
+// a single file, holding either just the bucket name or key=value lines for bucket, prefix, region and endpoint.
+func readReferencedDestination(target *zen_targets.Target) (S3Destination, error) {
+	files := target.Srcs[destinationSrc]
+	if len(files) != 1 {
+		return S3Destination{}, fmt.Errorf("the bucket reference has to output a single file, got %d", len(files))
+	}
+
+	f, err := os.Open(referencedFilePath(target, files[0]))
+	if err != nil {
+		return S3Destination{}, fmt.Errorf("reading the bucket reference: %w", err)
+	}
+	defer f.Close()
+
+	dest := S3Destination{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			dest.Bucket = line
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "bucket":
+			dest.Bucket = strings.TrimSpace(val)
+		case "prefix":
+			dest.BucketPrefix = strings.TrimSpace(val)
+		case "region":
+			dest.Region = strings.TrimSpace(val)
+		case "endpoint":
+			dest.Endpoint = strings.TrimSpace(val)
+		default:
+			return S3Destination{}, fmt.Errorf("unknown key %q in the bucket reference", key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return S3Destination{}, fmt.Errorf("reading the bucket reference: %w", err)
+	}
+
+	if dest.Bucket == "" {
+		return S3Destination{}, fmt.Errorf("the bucket reference does not provide a bucket")
+	}
+
+	return dest, nil
+}
+
+func referencedFilePath(target *zen_targets.Target, f string) string {
+	if filepath.IsAbs(f) {
+		return f
+	}
+	return filepath.Join(target.Cwd, f)
+}
+
+// isReferencedDestinationFile checks whether the out is the file describing the referenced destination,
+// which is not meant to be uploaded
+func isReferencedDestinationFile(target *zen_targets.Target, out string) bool {
+	for _, f := range target.Srcs[destinationSrc] {
+		if referencedFilePath(target, f) == out {
+			return true
+		}
+	}
+
+	return false
+}
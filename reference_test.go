@@ -0,0 +1,80 @@
+package s3
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+func TestReferencedDestinationIsADependency(t *testing.T) {
+	fc := testConfig("")
+	fc.Bucket = "//infra:prod-assets-bucket"
+	targets, err := fc.GetTargets(&zen_targets.TargetConfigContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := targets[0].Srcs[destinationSrc]; !reflect.DeepEqual(got, []string{fc.Bucket}) {
+		t.Errorf("destination srcs %v, want the reference", got)
+	}
+	found := false
+	for _, dep := range targets[0].Deps {
+		found = found || dep == fc.Bucket
+	}
+	if !found {
+		t.Errorf("deps %v do not include the reference", targets[0].Deps)
+	}
+}
+
+func TestReferencedDestination(t *testing.T) {
+	for name, content := range map[string]string{
+		"bucket name": "prod-assets\n",
+		"key values":  "# provided by //infra\nbucket = prod-assets\nprefix=static\nregion=eu-west-1\n",
+	} {
+		fake := newFakeS3()
+		useFake(t, fake)
+
+		fc := testConfig("")
+		fc.Bucket = "//infra:prod-assets-bucket"
+		target := testTarget(t, fc, map[string]string{"index.html": "<html></html>", "infra/bucket.txt": content})
+		target.Srcs = map[string][]string{destinationSrc: {"infra/bucket.txt"}}
+
+		if err := runScript(t, fc, "deploy", target, nil); err != nil {
+			t.Fatalf("%s: deploy: %v", name, err)
+		}
+
+		inputs := fake.inputs("PutObject")
+		if len(inputs) != 1 {
+			t.Fatalf("%s: uploaded %v, want only index.html and not the destination file", name, fake.keys("PutObject"))
+		}
+		input := inputs[0].(*s3.PutObjectInput)
+		if bucket := aws.ToString(input.Bucket); bucket != "prod-assets" {
+			t.Errorf("%s: uploaded to bucket %q, want the referenced one", name, bucket)
+		}
+		want := "index.html"
+		if strings.Contains(content, "prefix=") {
+			want = "static/index.html"
+		}
+		if key := aws.ToString(input.Key); key != want {
+			t.Errorf("%s: uploaded to %q, want %q", name, key, want)
+		}
+	}
+}
+
+func TestReferencedDestinationWithoutBucket(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("")
+	fc.Bucket = "//infra:prod-assets-bucket"
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>", "infra/bucket.txt": "region=eu-west-1\n"})
+	target.Srcs = map[string][]string{destinationSrc: {"infra/bucket.txt"}}
+
+	if err := runScript(t, fc, "deploy", target, nil); err == nil || !strings.Contains(err.Error(), "does not provide a bucket") {
+		t.Errorf("got %v, want the missing bucket to be reported", err)
+	}
+}
@@ -0,0 +1,42 @@
+package s3
+
+import (
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+// providerRegions are the regions S3 compatible providers expect requests to be signed for,
+// regardless of where the bucket actually is
+var providerRegions = map[string]string{
+	"aws":          "",
+	"cloudflare":   "auto",
+	"digitalocean": "us-east-1",
+	"gcs":          "auto",
+	"minio":        "us-east-1",
+	"wasabi":       "us-east-1",
+}
+
+// compatibleRegion is the region S3 compatible stores accept when nothing else is known
+const compatibleRegion = "us-east-1"
+
+// resolveRegion picks the region to sign requests for: the one of the destination or the target, then the default
+// of the provider, then AWS_REGION/AWS_DEFAULT_REGION from the target env. With a custom endpoint, it falls back to
+// us-east-1, otherwise it is left empty for the sdk to resolve from the process env and profile.
+func resolveRegion(target *zen_targets.Target, fc S3FileConfig, dest S3Destination, endpoint string) string {
+	for _, region := range []string{
+		dest.Region,
+		fc.Region,
+		providerRegions[fc.Provider],
+		target.Env["AWS_REGION"],
+		target.Env["AWS_DEFAULT_REGION"],
+	} {
+		if region != "" {
+			return region
+		}
+	}
+
+	if endpoint != "" {
+		return compatibleRegion
+	}
+
+	return ""
+}
@@ -0,0 +1,38 @@
+package s3
+
+import (
+	"testing"
+)
+
+func TestResolveRegion(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		destRegion       string
+		region, provider string
+		env              map[string]string
+		endpoint         string
+		want             string
+	}{
+		{name: "destination first", destRegion: "eu-west-1", region: "us-west-2", provider: "cloudflare", want: "eu-west-1"},
+		{name: "explicit region", region: "us-west-2", provider: "cloudflare", env: map[string]string{"AWS_REGION": "eu-central-1"}, want: "us-west-2"},
+		{name: "provider default", provider: "cloudflare", env: map[string]string{"AWS_REGION": "eu-central-1"}, want: "auto"},
+		{name: "provider default over the env", provider: "minio", env: map[string]string{"AWS_REGION": "eu-central-1"}, want: "us-east-1"},
+		{name: "aws defers to the env", provider: "aws", env: map[string]string{"AWS_REGION": "eu-central-1"}, want: "eu-central-1"},
+		{name: "default region env", env: map[string]string{"AWS_DEFAULT_REGION": "ap-south-1"}, want: "ap-south-1"},
+		{name: "AWS_REGION over AWS_DEFAULT_REGION", env: map[string]string{"AWS_REGION": "eu-central-1", "AWS_DEFAULT_REGION": "ap-south-1"}, want: "eu-central-1"},
+		{name: "compatible literal", endpoint: "https://s3.example.com", want: compatibleRegion},
+		{name: "left to the sdk", want: ""},
+	} {
+		fc := testConfig("site")
+		fc.Region = tc.region
+		fc.Provider = tc.provider
+		target := testTarget(t, fc, nil)
+		for k, v := range tc.env {
+			target.Env[k] = v
+		}
+
+		if got := resolveRegion(target, fc, S3Destination{Region: tc.destRegion}, tc.endpoint); got != tc.want {
+			t.Errorf("%s: got region %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
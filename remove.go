@@ -0,0 +1,309 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// remoteObject is an object the remove script deletes
+type remoteObject struct {
+	key  string
+	size int64
+	// etag recorded in the manifest, if any
+	etag string
+}
+
+func (fc S3FileConfig) remove(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) error {
+	keyTemplate, err := fc.resolveKeyTemplate(target, runCtx)
+	if err != nil {
+		return fmt.Errorf("interpolating key template: %w", err)
+	}
+	fc.KeyTemplate = keyTemplate
+
+	dests, err := fc.destinations(target, runCtx)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.TODO()
+	errs := []error{}
+	for _, dest := range dests {
+		if err := fc.removeFrom(ctx, target, runCtx, dest); err != nil {
+			errs = append(errs, fmt.Errorf("removing from %s: %w", dest, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// removeFrom deletes the objects of the target from a single destination
+func (fc S3FileConfig) removeFrom(ctx context.Context, target *zen_targets.Target, runCtx *zen_targets.RuntimeContext, dest destination) error {
+	client, bucket := dest.client, dest.bucket
+
+	if fc.Preflight {
+		if err := fc.preflight(target, client, bucket); err != nil {
+			return err
+		}
+	}
+
+	objects, err := fc.removalObjects(ctx, target, runCtx, dest)
+	if err != nil {
+		return err
+	}
+
+	// checked on dry runs too, so they show what would be refused
+	if fc.MaxDeletePercent > 0 && !fc.Force {
+		if err := fc.checkDeletePercent(ctx, dest, objects); err != nil {
+			return err
+		}
+	}
+
+	onDeleted := fc.UploadOptions.withDefaults(target).OnDeleted
+	action, batchSize := planDelete, maxDeleteBatch
+	process := func(ctx context.Context, batch []remoteObject) []error {
+		return deleteBatch(ctx, target, client, bucket, batch, onDeleted)
+	}
+	if fc.SoftDeleteTag != "" {
+		// tagging takes a request per object
+		action, batchSize = planSoftDelete, 1
+		process = func(ctx context.Context, batch []remoteObject) []error {
+			return fc.tagBatch(ctx, target, client, bucket, batch, onDeleted)
+		}
+	} else if fc.ConditionalDelete {
+		// conditions only apply to single object deletes
+		batchSize = 1
+		process = func(ctx context.Context, batch []remoteObject) []error {
+			return deleteIfMatch(ctx, target, client, bucket, batch, onDeleted)
+		}
+	}
+
+	if runCtx.DryRun {
+		for _, obj := range objects {
+			target.Infoln("%s s3://%s/%s (%d bytes)", action, bucket, obj.key, obj.size)
+		}
+		return nil
+	}
+
+	batches := deleteBatches(objects, batchSize)
+
+	// Create a WaitGroup to manage concurrency
+	var wg sync.WaitGroup
+
+	// Create a buffered channel to control concurrency
+	sem := make(chan struct{}, fc.parallelism(len(batches)))
+
+	var errs multiError
+
+	for _, batch := range batches {
+		wg.Add(1)
+
+		// Acquire a token from the semaphore
+		sem <- struct{}{}
+
+		go func(batch []remoteObject) {
+			// Decrement the counter when the goroutine completes
+			defer wg.Done()
+			// Release a token back to the semaphore
+			defer func() { <-sem }()
+
+			errs.add(process(ctx, batch)...)
+		}(batch)
+	}
+
+	// Wait for all deletions to complete
+	wg.Wait()
+	return errs.err()
+}
+
+// maxDeleteBatch is the maximum amount of keys accepted by a single DeleteObjects request
+const maxDeleteBatch = 1000
+
+func deleteBatches(objects []remoteObject, size int) [][]remoteObject {
+	batches := [][]remoteObject{}
+	for len(objects) > size {
+		batches = append(batches, objects[:size])
+		objects = objects[size:]
+	}
+	if len(objects) > 0 {
+		batches = append(batches, objects)
+	}
+
+	return batches
+}
+
+// deleteBatch deletes the objects in a single request, returning an error for every key that could not be deleted
+func deleteBatch(ctx context.Context, target *zen_targets.Target, client s3API, bucket string, batch []remoteObject, onDeleted func(ObjectEvent)) []error {
+	identifiers := make([]types.ObjectIdentifier, 0, len(batch))
+	for _, obj := range batch {
+		identifiers = append(identifiers, types.ObjectIdentifier{Key: aws.String(obj.key)})
+	}
+
+	start := time.Now()
+	out, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(bucket),
+		Delete: &types.Delete{
+			Objects: identifiers,
+			Quiet:   true,
+		},
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to delete %d objects, %v", len(batch), err)
+		notifyDeleted(onDeleted, bucket, batch, time.Since(start), nil, err)
+		return []error{err}
+	}
+
+	errs := []error{}
+	failed := map[string]error{}
+	absent := 0
+	for _, e := range out.Errors {
+		// removing is idempotent, an object that is already gone is what we wanted
+		if isNotFoundCode(aws.ToString(e.Code)) {
+			target.Debugln("s3://%s/%s: already absent", bucket, aws.ToString(e.Key))
+			absent++
+			continue
+		}
+		err := fmt.Errorf("failed to delete %q, %s: %s", aws.ToString(e.Key), aws.ToString(e.Code), aws.ToString(e.Message))
+		failed[aws.ToString(e.Key)] = err
+		errs = append(errs, err)
+	}
+	notifyDeleted(onDeleted, bucket, batch, time.Since(start), failed, nil)
+
+	target.Debugln("deleted %d objects from s3://%s", len(batch)-len(errs), bucket)
+	if absent > 0 {
+		target.Debugln("%d objects were already absent", absent)
+	}
+	return errs
+}
+
+// notifyDeleted reports the deletion of each object of a batch to onDeleted, with the errors of the
+// keys that failed, or err for all of them
+func notifyDeleted(onDeleted func(ObjectEvent), bucket string, batch []remoteObject, duration time.Duration, failed map[string]error, err error) {
+	for _, obj := range batch {
+		event := ObjectEvent{Bucket: bucket, Key: obj.key, Size: obj.size, Duration: duration, Err: err}
+		if keyErr, ok := failed[obj.key]; ok {
+			event.Err = keyErr
+		}
+		onDeleted(event)
+	}
+}
+
+// isNotFoundCode reports whether a per key error code means the object does not exist.
+// S3 itself reports missing keys as deleted, but some compatible stores return an error for them.
+func isNotFoundCode(code string) bool {
+	switch code {
+	case "NoSuchKey", "NotFound", "404":
+		return true
+	}
+	return false
+}
+
+// isNotFound reports whether the error of a single object delete means the object does not exist
+func isNotFound(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && isNotFoundCode(apiErr.ErrorCode()) {
+		return true
+	}
+
+	var respErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound
+}
+
+// removalObjects lists the objects stored under the prefix, so removing works even once the local artifacts are gone.
+// Without a prefix this would match the whole bucket, so the keys are derived from the outs instead.
+func (fc S3FileConfig) removalObjects(ctx context.Context, target *zen_targets.Target, runCtx *zen_targets.RuntimeContext, dest destination) ([]remoteObject, error) {
+	client, bucket, prefix := dest.client, dest.bucket, dest.prefix
+	objects := []remoteObject{}
+
+	if fc.RecordManifest {
+		key := fc.manifestKey(prefix)
+		manifest, err := readManifest(ctx, client, bucket, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if manifest != nil {
+			for _, k := range manifest.Keys {
+				objects = append(objects, remoteObject{key: k, etag: manifest.ETags[k]})
+			}
+			if fc.UploadReceipt {
+				objects = append(objects, remoteObject{key: path.Join(prefix, receiptName)})
+			}
+			if fc.VersionPointer != "" {
+				objects = append(objects, remoteObject{key: fc.versionPointerKey(prefix)})
+			}
+			return append(objects, remoteObject{key: key}), nil
+		}
+
+		target.Debugln("no deploy manifest found at s3://%s/%s, falling back to the objects under the prefix", bucket, key)
+	}
+
+	// the enforced prefix is shared with other targets, so it counts as no prefix at all
+	if p := strings.Trim(prefix, "/"); p == "" || p == enforcedPrefix() {
+		outs, err := fc.uploadableOuts(target)
+		if err != nil {
+			return nil, err
+		}
+
+		// the keys a deploy would upload to, archive, inline content, redirects and aliases included
+		d, err := fc.newDeployment(target, runCtx, dest, nil)
+		if err != nil {
+			return nil, err
+		}
+		d.eachUploadJob(outs, func(job uploadJob) bool {
+			obj := remoteObject{key: job.key, size: job.size}
+			if job.file != "" {
+				if info, err := os.Stat(job.file); err == nil {
+					obj.size = info.Size()
+				}
+			}
+			objects = append(objects, obj)
+
+			// only files are copied to the aliases
+			if job.file != "" {
+				for _, alias := range d.aliasKeys(job.key) {
+					objects = append(objects, remoteObject{key: alias, size: obj.size})
+				}
+			}
+			return true
+		})
+		if fc.SitemapBaseURL != "" {
+			objects = append(objects, remoteObject{key: d.contentKey(sitemapName)})
+		}
+		if d.pointerKey != "" {
+			objects = append(objects, remoteObject{key: d.pointerKey})
+		}
+
+		return objects, nil
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(strings.TrimSuffix(prefix, "/") + "/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing objects under %q: %w", prefix, err)
+		}
+
+		for _, obj := range page.Contents {
+			objects = append(objects, remoteObject{key: aws.ToString(obj.Key), size: obj.Size})
+		}
+	}
+
+	return objects, nil
+}
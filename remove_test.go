@@ -0,0 +1,108 @@
+package s3
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+// removeWithErrors deploys three files, then removes them with a not found error for a.txt and
+// a permission error for b.txt, injected on op
+func removeWithErrors(t *testing.T, fc S3FileConfig, op string) (*fakeS3, *zen_targets.Target, error) {
+	t.Helper()
+
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	target := testTarget(t, fc, map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"})
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	fake.fail(op, "site/a.txt", noSuchKeyError())
+	fake.fail(op, "site/b.txt", accessDeniedError())
+
+	return fake, target, runScript(t, fc, "remove", target, nil)
+}
+
+func TestRemoveTreatsMissingKeysAsRemoved(t *testing.T) {
+	fake, target, err := removeWithErrors(t, testConfig("site"), "DeleteObjects")
+	if err == nil {
+		t.Fatal("remove succeeded despite the permission error")
+	}
+	if !strings.Contains(err.Error(), "site/b.txt") || strings.Contains(err.Error(), "site/a.txt") {
+		t.Errorf("got error %v, want only the permission error of site/b.txt", err)
+	}
+	if fake.object("site/c.txt") != nil {
+		t.Error("site/c.txt was not removed")
+	}
+	// the absent object counts as deleted, only the denied one does not
+	if !logged(target, "deleted 2 objects") || !logged(target, "1 objects were already absent") {
+		t.Errorf("got logs %v, want 2 objects deleted, 1 of them already absent", target.Logs)
+	}
+}
+
+func TestRemoveBatchesDeletions(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	files := map[string]string{}
+	for i := 0; i < 1500; i++ {
+		files[fmt.Sprintf("f%04d.txt", i)] = "x"
+	}
+	target := testTarget(t, fc, files)
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	if err := runScript(t, fc, "remove", target, nil); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	batches := fake.inputs("DeleteObjects")
+	if len(batches) != 2 {
+		t.Fatalf("made %d DeleteObjects calls, want 2", len(batches))
+	}
+	sizes := []int{}
+	for _, in := range batches {
+		sizes = append(sizes, len(in.(*s3.DeleteObjectsInput).Delete.Objects))
+	}
+	sort.Ints(sizes)
+	if sizes[0] != 500 || sizes[1] != maxDeleteBatch {
+		t.Errorf("got batches of %v keys, want 500 and %d", sizes, maxDeleteBatch)
+	}
+	if fake.count("DeleteObject") != 0 {
+		t.Errorf("made %d single DeleteObject calls", fake.count("DeleteObject"))
+	}
+	if got := fake.stored(); len(got) != 0 {
+		t.Errorf("left %d objects after remove", len(got))
+	}
+}
+
+func TestRemoveWithoutLocalFiles(t *testing.T) {
+	fc := testConfig("site")
+	fake, target := deployFiles(t, fc, map[string]string{"index.html": "<html></html>", "css/site.css": "body {}"})
+	fake.put("site/stale.txt", "from an older deploy")
+	fake.put("other/keep.txt", "outside of the prefix")
+
+	// the artifacts were cleaned up since the deploy
+	for _, out := range target.Outs {
+		if err := os.Remove(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := runScript(t, fc, "remove", target, nil); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if got, want := fake.stored(), []string{"other/keep.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("left %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+// sentHeaders runs calls with the client fc creates against a local server, returning the headers of every
+// request it received. The server answers with empty responses, so the calls are not expected to succeed.
+func sentHeaders(t *testing.T, fc S3FileConfig, calls func(ctx context.Context, client *s3.Client)) []http.Header {
+	t.Helper()
+
+	var mu sync.Mutex
+	headers := []http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Clone())
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		Retryer:     func() aws.Retryer { return aws.NopRetryer{} },
+		EndpointResolverWithOptions: aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: server.URL, HostnameImmutable: true}, nil
+		}),
+	}
+	calls(context.Background(), newS3Client(cfg, fc))
+
+	mu.Lock()
+	defer mu.Unlock()
+	return headers
+}
+
+// everyOperation makes the calls a deploy and a remove rely on
+func everyOperation(ctx context.Context, client *s3.Client) {
+	client.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String(testBucket), Key: aws.String("site/a.txt")})
+	client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{Bucket: aws.String(testBucket), Key: aws.String("site/big.bin")})
+	client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: aws.String(testBucket), Prefix: aws.String("site/")})
+	client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(testBucket), Key: aws.String("site/a.txt")})
+	client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(testBucket),
+		Delete: &types.Delete{Objects: []types.ObjectIdentifier{{Key: aws.String("site/a.txt")}}},
+	})
+}
+
+func TestRequestPayer(t *testing.T) {
+	fc := testConfig("site")
+	fc.RequestPayer = true
+
+	headers := sentHeaders(t, fc, everyOperation)
+	if len(headers) != 5 {
+		t.Fatalf("sent %d requests, want 5", len(headers))
+	}
+	for i, h := range headers {
+		if got := h.Get("X-Amz-Request-Payer"); got != "requester" {
+			t.Errorf("request %d sent request payer %q, want requester", i, got)
+		}
+	}
+
+	for i, h := range sentHeaders(t, testConfig("site"), everyOperation) {
+		if got := h.Get("X-Amz-Request-Payer"); got != "" {
+			t.Errorf("request %d sent request payer %q without request_payer", i, got)
+		}
+	}
+}
+
+func TestExpectedBucketOwner(t *testing.T) {
+	fc := testConfig("site")
+	fc.ExpectedBucketOwner = "123456789012"
+
+	headers := sentHeaders(t, fc, everyOperation)
+	if len(headers) != 5 {
+		t.Fatalf("sent %d requests, want 5", len(headers))
+	}
+	for i, h := range headers {
+		if got := h.Get("X-Amz-Expected-Bucket-Owner"); got != "123456789012" {
+			t.Errorf("request %d sent expected bucket owner %q", i, got)
+		}
+	}
+
+	for _, owner := range []string{"1234", "12345678901a"} {
+		fc.ExpectedBucketOwner = owner
+		if _, err := fc.GetTargets(&zen_targets.TargetConfigContext{}); err == nil || !strings.Contains(err.Error(), "expected_bucket_owner") {
+			t.Errorf("expected_bucket_owner %q: got %v, want a configuration error", owner, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// resumeMultipartUpload completes the multipart upload of key that an earlier deploy left behind, reusing the parts
+// whose ETag matches the md5 of the same part of body and uploading the rest. It is best effort, and returns a nil
+// output without having read body when there is nothing to resume, so the uploader starts over as usual.
+// partSize is the part size the uploader would pick for size, see uploadPartSize.
+//
+// The uploader does not expose resuming, so this drives the multipart upload itself, with a few limits:
+//   - parts are only reused when they were uploaded with the same part size, which is what the uploader picks
+//     for a file of the same size
+//   - the ETag of a part is only its md5 without SSE-KMS or SSE-C, so those uploads never reuse a part
+//   - uploads created with a checksum algorithm are not resumed
+//   - the remaining parts are uploaded one at a time instead of part_concurrency at a time
+//   - the object gets the settings (content type, metadata, acl...) of the interrupted upload, not the current ones
+func (d *deployment) resumeMultipartUpload(ctx context.Context, input *s3.PutObjectInput, body io.ReadSeeker, size, partSize int64) (*manager.UploadOutput, error) {
+	key := aws.ToString(input.Key)
+
+	upload, err := d.pendingMultipartUpload(ctx, key)
+	if err != nil || upload == nil {
+		return nil, err
+	}
+	uploadID := aws.ToString(upload.UploadId)
+
+	existing := map[int32]types.Part{}
+	parts := s3.NewListPartsPaginator(d.client, &s3.ListPartsInput{
+		Bucket:   aws.String(d.bucket),
+		Key:      aws.String(key),
+		UploadId: upload.UploadId,
+	})
+	for parts.HasMorePages() {
+		page, err := parts.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing parts of upload %s of s3://%s/%s: %w", uploadID, d.bucket, key, err)
+		}
+		for _, part := range page.Parts {
+			existing[part.PartNumber] = part
+		}
+	}
+
+	if first, ok := existing[1]; ok && first.Size != partSize {
+		d.debugln(key, "not resuming upload %s, its parts are %d bytes instead of %d", uploadID, first.Size, partSize)
+		return nil, nil
+	}
+
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	completed := []types.CompletedPart{}
+	reused := 0
+	buf := make([]byte, partSize)
+	for number := int32(1); int64(number-1)*partSize < size; number++ {
+		n, err := io.ReadFull(body, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("reading part %d: %w", number, err)
+		}
+		part := buf[:n]
+
+		sum := md5.Sum(part)
+		if prev, ok := existing[number]; ok && prev.Size == int64(n) && strings.Trim(aws.ToString(prev.ETag), `"`) == hex.EncodeToString(sum[:]) {
+			completed = append(completed, types.CompletedPart{ETag: prev.ETag, PartNumber: number})
+			reused++
+			continue
+		}
+
+		out, err := d.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(d.bucket),
+			Key:           aws.String(key),
+			UploadId:      upload.UploadId,
+			PartNumber:    number,
+			Body:          bytes.NewReader(part),
+			ContentLength: int64(n),
+		})
+		if err != nil {
+			// the parts uploaded so far are kept for the next attempt
+			return nil, fmt.Errorf("uploading part %d of upload %s: %w", number, uploadID, err)
+		}
+		completed = append(completed, types.CompletedPart{ETag: out.ETag, PartNumber: number})
+	}
+
+	d.debugln(key, "resuming upload %s, reused %d of %d parts", uploadID, reused, len(completed))
+
+	out, err := d.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(d.bucket),
+		Key:             aws.String(key),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("completing upload %s: %w", uploadID, err)
+	}
+
+	return &manager.UploadOutput{
+		Location:       aws.ToString(out.Location),
+		UploadID:       uploadID,
+		CompletedParts: completed,
+		ETag:           out.ETag,
+		VersionID:      out.VersionId,
+		Key:            out.Key,
+	}, nil
+}
+
+// pendingMultipartUpload finds the most recent multipart upload of key that can be resumed, if any
+func (d *deployment) pendingMultipartUpload(ctx context.Context, key string) (*types.MultipartUpload, error) {
+	var latest *types.MultipartUpload
+	var latestInitiated time.Time
+
+	uploads := s3.NewListMultipartUploadsPaginator(d.client, &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(d.bucket),
+		Prefix: aws.String(key),
+	})
+	for uploads.HasMorePages() {
+		page, err := uploads.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing multipart uploads of s3://%s/%s: %w", d.bucket, key, err)
+		}
+
+		for i, upload := range page.Uploads {
+			// the prefix also matches longer keys
+			if aws.ToString(upload.Key) != key || upload.ChecksumAlgorithm != "" {
+				continue
+			}
+			if initiated := aws.ToTime(upload.Initiated); latest == nil || initiated.After(latestInitiated) {
+				latest, latestInitiated = &page.Uploads[i], initiated
+			}
+		}
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,68 @@
+package s3
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+)
+
+func TestResumeSkipsUploadedParts(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.ResumeUploads = true
+	// three parts, the last one short
+	partSize := int(manager.DefaultUploadPartSize)
+	content := bytes.Repeat([]byte("0123456789abcdef"), (2*partSize+1024)/16)
+	target := testTarget(t, fc, map[string]string{"big.bin": string(content)})
+
+	// an earlier deploy uploaded the first part, and a second one that does not match the file anymore
+	stale := bytes.Repeat([]byte("x"), partSize)
+	fake.startUpload("site/big.bin", content[:partSize], stale)
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	if n := fake.count("CreateMultipartUpload"); n != 0 {
+		t.Errorf("started %d new uploads instead of resuming", n)
+	}
+	if n := fake.count("UploadPart"); n != 2 {
+		t.Errorf("uploaded %d parts, want the stale and the missing one", n)
+	}
+	if obj := fake.object("site/big.bin"); obj == nil || !bytes.Equal(obj.body, content) {
+		t.Error("the resumed object does not hold the file")
+	}
+	if len(fake.uploads) != 0 {
+		t.Errorf("left %d multipart uploads behind", len(fake.uploads))
+	}
+}
+
+func TestResumeStartsOverWithOtherPartSizes(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.ResumeUploads = true
+	partSize := int(manager.DefaultUploadPartSize)
+	content := bytes.Repeat([]byte("0123456789abcdef"), (partSize+1024)/16)
+	target := testTarget(t, fc, map[string]string{"big.bin": string(content)})
+
+	fake.startUpload("site/big.bin", content[:1024])
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	if n := fake.count("CompleteMultipartUpload"); n != 1 {
+		t.Errorf("completed %d uploads, want 1", n)
+	}
+	if n := fake.count("CreateMultipartUpload"); n != 1 {
+		t.Errorf("started %d uploads, want the upload to start over", n)
+	}
+	if obj := fake.object("site/big.bin"); obj == nil || !bytes.Equal(obj.body, content) {
+		t.Error("the object does not hold the file")
+	}
+}
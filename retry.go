@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// maxRetryAfter caps the delay requested by the server, so a bogus header cannot stall a deploy
+const maxRetryAfter = time.Minute
+
+// retryAfterBackoff honors the Retry-After header sent along with 503 responses,
+// deferring to the fallback backoff for every other error
+type retryAfterBackoff struct {
+	fallback retry.BackoffDelayer
+	now      func() time.Time
+}
+
+func (b retryAfterBackoff) BackoffDelay(attempt int, err error) (time.Duration, error) {
+	// errors built without a response, as some middlewares do, have no header nor status to go by
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) && respErr.Response != nil && respErr.Response.Response != nil && respErr.HTTPStatusCode() == http.StatusServiceUnavailable {
+		if delay, ok := parseRetryAfter(respErr.Response.Header.Get("Retry-After"), b.now()); ok {
+			return delay, nil
+		}
+	}
+
+	return b.fallback.BackoffDelay(attempt, err)
+}
+
+// parseRetryAfter reads a Retry-After value, which can either be a number of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = date.Sub(now)
+	} else {
+		return 0, false
+	}
+
+	if delay < 0 {
+		delay = 0
+	} else if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+
+	return delay, true
+}
+
+func newRetryAfterRetryer() func() aws.Retryer {
+	return func() aws.Retryer {
+		return retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retryAfterBackoff{
+				fallback: retry.NewExponentialJitterBackoff(o.MaxBackoff),
+				now:      time.Now,
+			}
+		})
+	}
+}
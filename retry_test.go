@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+type fixedBackoff time.Duration
+
+func (b fixedBackoff) BackoffDelay(attempt int, err error) (time.Duration, error) {
+	return time.Duration(b), nil
+}
+
+// withoutResponse is a response error carrying no http response, as built by some middlewares
+func withoutResponse(inner *http.Response) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: inner},
+			Err:      errors.New("failed"),
+		},
+	}
+}
+
+func TestBackoffDelayHonorsRetryAfter(t *testing.T) {
+	b := retryAfterBackoff{fallback: fixedBackoff(time.Second), now: time.Now}
+
+	delay, err := b.BackoffDelay(1, responseError(http.StatusServiceUnavailable, http.Header{"Retry-After": {"7"}}, errors.New("slow down")))
+	if err != nil || delay != 7*time.Second {
+		t.Errorf("got %v, %v, want the 7s of the header", delay, err)
+	}
+
+	delay, err = b.BackoffDelay(1, responseError(http.StatusInternalServerError, http.Header{"Retry-After": {"7"}}, errors.New("failed")))
+	if err != nil || delay != time.Second {
+		t.Errorf("got %v, %v, want the fallback for other statuses", delay, err)
+	}
+}
+
+func TestBackoffDelayWithoutResponse(t *testing.T) {
+	b := retryAfterBackoff{fallback: fixedBackoff(time.Second), now: time.Now}
+
+	delay, err := b.BackoffDelay(1, withoutResponse(nil))
+	if err != nil || delay != time.Second {
+		t.Errorf("got %v, %v, want the fallback", delay, err)
+	}
+}
@@ -0,0 +1,90 @@
+package s3
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/bmatcuk/doublestar/v4"
+	"golang.org/x/exp/slices"
+)
+
+// S3ObjectRule overrides the headers of the objects whose path matches a glob
+type S3ObjectRule struct {
+	Match              string            `mapstructure:"match" desc:"Glob of the file paths the rule applies to, e.g. **/*.js"`
+	CacheControl       string            `mapstructure:"cache_control" desc:"Cache-Control header"`
+	ContentType        string            `mapstructure:"content_type" desc:"Content-Type header"`
+	ContentEncoding    string            `mapstructure:"content_encoding" desc:"Content-Encoding header"`
+	ContentDisposition string            `mapstructure:"content_disposition" desc:"Content-Disposition header"`
+	ACL                string            `mapstructure:"acl" desc:"Canned ACL"`
+	StorageClass       string            `mapstructure:"storage_class" desc:"Storage class"`
+	RedirectLocation   string            `mapstructure:"redirect_location" desc:"Website redirect location"`
+	Metadata           map[string]string `mapstructure:"metadata" desc:"User metadata, added to that of previous rules"`
+}
+
+func (r S3ObjectRule) validate() error {
+	if !doublestar.ValidatePattern(r.Match) {
+		return fmt.Errorf("invalid glob %q", r.Match)
+	}
+	if err := validateContentEncoding(r.ContentEncoding); err != nil {
+		return err
+	}
+	if r.ACL != "" && !slices.Contains(types.ObjectCannedACL("").Values(), types.ObjectCannedACL(r.ACL)) {
+		return fmt.Errorf("acl must be one of %v, got %q", types.ObjectCannedACL("").Values(), r.ACL)
+	}
+	if r.StorageClass != "" {
+		if err := validateStorageClass(r.StorageClass); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply sets the headers of the rule on the upload request
+func (r S3ObjectRule) apply(input *s3.PutObjectInput) {
+	if r.CacheControl != "" {
+		input.CacheControl = aws.String(r.CacheControl)
+	}
+	if r.ContentType != "" {
+		input.ContentType = aws.String(r.ContentType)
+	}
+	if r.ContentEncoding != "" {
+		input.ContentEncoding = aws.String(r.ContentEncoding)
+	}
+	if r.ContentDisposition != "" {
+		input.ContentDisposition = aws.String(r.ContentDisposition)
+	}
+	if r.ACL != "" {
+		// s3 rejects a canned acl sent along with grants
+		input.ACL = types.ObjectCannedACL(r.ACL)
+		input.GrantRead, input.GrantReadACP, input.GrantWriteACP, input.GrantFullControl = nil, nil, nil, nil
+	}
+	if r.StorageClass != "" {
+		input.StorageClass = types.StorageClass(r.StorageClass)
+	}
+	if r.RedirectLocation != "" {
+		input.WebsiteRedirectLocation = aws.String(r.RedirectLocation)
+	}
+	if len(r.Metadata) > 0 {
+		if input.Metadata == nil {
+			input.Metadata = map[string]string{}
+		}
+		for k, v := range r.Metadata {
+			input.Metadata[k] = v
+		}
+	}
+}
+
+// applyRules applies the rules matching rel in order, so later rules win over earlier ones and over
+// the other object settings
+func (fc S3FileConfig) applyRules(input *s3.PutObjectInput, rel string) {
+	rel = filepath.ToSlash(rel)
+	for _, rule := range fc.Rules {
+		if ok, _ := doublestar.Match(rule.Match, rel); ok {
+			rule.apply(input)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package s3
+
+import "testing"
+
+func TestRules(t *testing.T) {
+	fc := testConfig("site")
+	fc.Rules = []S3ObjectRule{
+		{Match: "**/*", CacheControl: "max-age=60", Metadata: map[string]string{"team": "web"}},
+		{Match: "**/*.js", CacheControl: "max-age=31536000", ContentType: "text/javascript", Metadata: map[string]string{"kind": "asset"}},
+		{Match: "assets/vendor/*.js", CacheControl: "no-cache"},
+		{Match: "*.html", ContentDisposition: "inline"},
+		{Match: "downloads/*.pdf", ContentDisposition: "attachment"},
+	}
+
+	fake, _ := deployFiles(t, fc, map[string]string{
+		"index.html":             "<html></html>",
+		"assets/app.js":          "app",
+		"assets/vendor/react.js": "react",
+		"downloads/guide.pdf":    "pdf",
+	})
+
+	for key, want := range map[string]fakeObject{
+		"site/index.html":             {cacheControl: "max-age=60", contentDisposition: "inline", metadata: map[string]string{"team": "web"}},
+		"site/assets/app.js":          {cacheControl: "max-age=31536000", contentType: "text/javascript", metadata: map[string]string{"team": "web", "kind": "asset"}},
+		"site/assets/vendor/react.js": {cacheControl: "no-cache", contentType: "text/javascript", metadata: map[string]string{"team": "web", "kind": "asset"}},
+		"site/downloads/guide.pdf":    {cacheControl: "max-age=60", contentDisposition: "attachment", metadata: map[string]string{"team": "web"}},
+	} {
+		obj := fake.object(key)
+		if obj == nil {
+			t.Errorf("%s not uploaded, stored %v", key, fake.stored())
+			continue
+		}
+
+		if obj.cacheControl != want.cacheControl {
+			t.Errorf("%s: got cache control %q, want %q", key, obj.cacheControl, want.cacheControl)
+		}
+		if want.contentType != "" && obj.contentType != want.contentType {
+			t.Errorf("%s: got content type %q, want %q", key, obj.contentType, want.contentType)
+		}
+		if obj.contentDisposition != want.contentDisposition {
+			t.Errorf("%s: got content disposition %q, want %q", key, obj.contentDisposition, want.contentDisposition)
+		}
+		for k, v := range want.metadata {
+			if obj.metadata[k] != v {
+				t.Errorf("%s: got metadata %v, want %v", key, obj.metadata, want.metadata)
+				break
+			}
+		}
+	}
+}
+
+func TestRulesApplyInOrder(t *testing.T) {
+	fc := testConfig("site")
+	fc.Rules = []S3ObjectRule{
+		{Match: "assets/vendor/*.js", CacheControl: "no-cache"},
+		{Match: "**/*.js", CacheControl: "max-age=31536000"},
+	}
+
+	fake, _ := deployFiles(t, fc, map[string]string{"assets/vendor/react.js": "react"})
+	if got := fake.object("site/assets/vendor/react.js").cacheControl; got != "max-age=31536000" {
+		t.Errorf("got cache control %q, want the one of the last matching rule", got)
+	}
+}
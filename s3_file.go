@@ -1,220 +1,474 @@
 package s3
 
 import (
-	"context"
 	"fmt"
-	"log"
-	"os"
-	"path/filepath"
+	"net/url"
 	"strings"
-	"sync"
+	"time"
 
 	environs "github.com/zen-io/zen-core/environments"
 	zen_targets "github.com/zen-io/zen-core/target"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 type S3FileConfig struct {
-	Name          string                           `mapstructure:"name" zen:"yes" desc:"Name for the target"`
-	Description   string                           `mapstructure:"desc" zen:"yes" desc:"Target description"`
-	Labels        []string                         `mapstructure:"labels" zen:"yes" desc:"Labels to apply to the targets"` //
-	Deps          []string                         `mapstructure:"deps" zen:"yes" desc:"Build dependencies"`
-	PassEnv       []string                         `mapstructure:"pass_env" zen:"yes" desc:"List of environment variable names that will be passed from the OS environment, they are part of the target hash"`
-	PassSecretEnv []string                         `mapstructure:"secret_env" zen:"yes" desc:"List of environment variable names that will be passed from the OS environment, they are not used to calculate the target hash"`
-	Env           map[string]string                `mapstructure:"env" zen:"yes" desc:"Key-Value map of static environment variables to be used"`
-	Tools         map[string]string                `mapstructure:"tools" zen:"yes" desc:"Key-Value map of tools to include when executing this target. Values can be references"`
-	Visibility    []string                         `mapstructure:"visibility" zen:"yes" desc:"List of visibility for this target"`
-	Environments  map[string]*environs.Environment `mapstructure:"environments" zen:"yes" desc:"Deployment Environments"`
-	MaxParallel   *int                             `mapstructure:"max_parallel" desc:"Maximum number of parallel uploads. Defaults to 10"`
-	Srcs          []string                         `mapstructure:"srcs"`
-	Bucket        string                           `mapstructure:"bucket"`
-	BucketPrefix  string                           `mapstructure:"bucket_prefix"`
+	Name                     string                           `mapstructure:"name" zen:"yes" desc:"Name for the target"`
+	Description              string                           `mapstructure:"desc" zen:"yes" desc:"Target description"`
+	Labels                   []string                         `mapstructure:"labels" zen:"yes" desc:"Labels to apply to the targets"` //
+	Deps                     []string                         `mapstructure:"deps" zen:"yes" desc:"Build dependencies"`
+	PassEnv                  []string                         `mapstructure:"pass_env" zen:"yes" desc:"List of environment variable names that will be passed from the OS environment, they are part of the target hash"`
+	PassSecretEnv            []string                         `mapstructure:"secret_env" zen:"yes" desc:"List of environment variable names that will be passed from the OS environment, they are not used to calculate the target hash"`
+	Env                      map[string]string                `mapstructure:"env" zen:"yes" desc:"Key-Value map of static environment variables to be used"`
+	Tools                    map[string]string                `mapstructure:"tools" zen:"yes" desc:"Key-Value map of tools to include when executing this target. Values can be references"`
+	Visibility               []string                         `mapstructure:"visibility" zen:"yes" desc:"List of visibility for this target"`
+	Environments             map[string]*environs.Environment `mapstructure:"environments" zen:"yes" desc:"Deployment Environments"`
+	MaxParallel              *int                             `mapstructure:"max_parallel" desc:"Maximum number of files uploaded in parallel. Defaults to 10"`
+	HonorRetryAfter          bool                             `mapstructure:"honor_retry_after" desc:"Wait for the delay in the Retry-After header of 503 responses instead of the default backoff"`
+	Incremental              bool                             `mapstructure:"incremental" desc:"Skip uploading files whose content matches the ETag of the remote object"`
+	Srcs                     []string                         `mapstructure:"srcs"`
+	Bucket                   string                           `mapstructure:"bucket" desc:"Bucket to upload to. Interpolated when deploying, so it can reference the environment, e.g. assets-{ENV}. It can also reference a target providing the bucket, e.g. //infra:assets-bucket"`
+	BucketPrefix             string                           `mapstructure:"bucket_prefix" desc:"Key prefix in the bucket. {{.OS}} and {{.Arch}} are replaced by the platform from the OS/GOOS and ARCH/GOARCH env vars"`
+	Tenant                   string                           `mapstructure:"tenant" desc:"Tenant id inserted after the bucket prefix of every key. Supports interpolation"`
+	SSE                      string                           `mapstructure:"sse" desc:"Server side encryption to apply to uploaded objects, either AES256 or aws:kms"`
+	SSEKMSKeyID              string                           `mapstructure:"sse_kms_key_id" desc:"KMS key used when sse is aws:kms"`
+	BucketKeyEnabled         *bool                            `mapstructure:"bucket_key_enabled" desc:"Use an S3 Bucket Key for SSE-KMS encryption. Defaults to the bucket configuration"`
+	FollowDirSymlinks        bool                             `mapstructure:"follow_dir_symlinks" desc:"Upload files found inside symlinked directories. Defaults to false"`
+	CloudFrontDistributionID string                           `mapstructure:"cloudfront_distribution_id" desc:"CloudFront distribution to invalidate after a deploy. Supports interpolation"`
+	InvalidationPaths        []string                         `mapstructure:"invalidation_paths" desc:"Paths to invalidate in the CloudFront distribution. Defaults to the uploaded keys"`
+	ContentDisposition       string                           `mapstructure:"content_disposition" desc:"Content-Disposition header for uploaded objects. Supports interpolation, including {BASENAME} for the file name"`
+	ContentDispositions      map[string]string                `mapstructure:"content_dispositions" desc:"Content-Disposition header per glob of the file path, overriding content_disposition"`
+	Compress                 bool                             `mapstructure:"compress" desc:"Gzip files before uploading them, setting Content-Encoding accordingly"`
+	FailFast                 bool                             `mapstructure:"fail_fast" desc:"Stop dispatching uploads after the first failure"`
+	DrainOnError             bool                             `mapstructure:"drain_on_error" desc:"When failing fast, let running uploads finish instead of cancelling them"`
+	VerifyDownloadSampleRate float64                          `mapstructure:"verify_download_sample_rate" desc:"Fraction (0 to 1) of uploaded objects to download again and compare with the local files"`
+	Flatten                  bool                             `mapstructure:"flatten" desc:"Upload every file directly under the prefix using its base name, instead of its path relative to the target"`
+	KeyCase                  string                           `mapstructure:"key_case" desc:"Casing applied to object keys: preserve, lower or upper. Defaults to preserve"`
+	ContentEncoding          string                           `mapstructure:"content_encoding" desc:"Content-Encoding header for all uploaded objects, for already compressed files"`
+	ContentEncodings         map[string]string                `mapstructure:"content_encodings" desc:"Content-Encoding header per glob of the file path, overriding content_encoding"`
+	ACL                      string                           `mapstructure:"acl" desc:"Canned ACL applied to uploaded objects"`
+	DetectObjectOwnership    *bool                            `mapstructure:"detect_object_ownership" desc:"Skip the acl, grants and acls of rules when the bucket enforces bucket owner object ownership. Defaults to true"`
+	ListMax                  int                              `mapstructure:"list_max" desc:"Maximum number of objects printed by the list script. Defaults to all of them"`
+	AccessKeyID              string                           `mapstructure:"access_key_id" desc:"Static access key id, overriding the default credential chain. Supports interpolation, so it can be read from secret_env"`
+	SecretAccessKey          string                           `mapstructure:"secret_access_key" desc:"Static secret access key, set together with access_key_id. Supports interpolation"`
+	ExcludeTools             bool                             `mapstructure:"exclude_tools" desc:"Do not upload files that belong to the tools of the target"`
+	IfModifiedSince          bool                             `mapstructure:"if_modified_since" desc:"Send the local modification time with the incremental HeadObject check, uploading objects not modified since then, which predate the local file, without hashing it. Only modified objects are compared by ETag"`
+	Endpoint                 string                           `mapstructure:"endpoint" desc:"S3 endpoint to use, taking precedence over the AWS_S3_ENDPOINT variable of the target env and then of the process env. When none is set, the SDK default is used. Supports interpolation"`
+	UploadOptions            *UploadOptions                   `mapstructure:"-"`
+	HTMLPaths                []string                         `mapstructure:"html_paths" desc:"Globs of extensionless files to serve as text/html, for pretty URLs"`
+	MaxInflightBytes         string                           `mapstructure:"max_inflight_bytes" desc:"Maximum amount of file bytes being uploaded at once, e.g. 512MB. Throttles dispatching of new uploads"`
+	RecordManifest           bool                             `mapstructure:"record_manifest" desc:"Record the deployed keys in a manifest object under the prefix, which remove uses to delete exactly those keys"`
+	ObjectLockMode           string                           `mapstructure:"object_lock_mode" desc:"Object Lock mode of uploaded objects, GOVERNANCE or COMPLIANCE"`
+	ObjectLockRetainUntil    string                           `mapstructure:"object_lock_retain_until" desc:"RFC3339 date until which uploaded objects are locked, required with object_lock_mode"`
+	Content                  map[string]string                `mapstructure:"content" desc:"Inline objects to upload, from key (relative to the prefix) to their content. Supports interpolation"`
+	InheritBucketACL         bool                             `mapstructure:"inherit_bucket_acl" desc:"When no acl is set, grant uploaded objects the same permissions as the bucket acl"`
+	VersionPointer           string                           `mapstructure:"version_pointer" desc:"Key, relative to the bucket prefix, of an object holding the deployed version. Objects are uploaded under <prefix>/<version> and the pointer is only updated once all of them were uploaded"`
+	Version                  string                           `mapstructure:"version" desc:"Version to upload the objects under when using version_pointer, e.g. {GIT_SHA}"`
+	AllowEmpty               bool                             `mapstructure:"allow_empty" desc:"Succeed when srcs match no files instead of failing the deploy"`
+	Preflight                bool                             `mapstructure:"preflight" desc:"Check the bucket is reachable before deploying or removing"`
+	PreflightTimeout         string                           `mapstructure:"preflight_timeout" desc:"Timeout of the pre-flight check, including its retries. Defaults to 10s"`
+	PreflightRetries         *int                             `mapstructure:"preflight_retries" desc:"Amount of times the pre-flight check is retried. Defaults to 2"`
+	Redirects                map[string]string                `mapstructure:"redirects" desc:"Website redirect location of the objects matching each key or glob. Keys matching no file are uploaded as empty objects carrying only the redirect"`
+	Destinations             []S3Destination                  `mapstructure:"destinations" desc:"Additional buckets, possibly in other regions, every file is uploaded to"`
+	SendContentMD5           bool                             `mapstructure:"send_content_md5" desc:"Send the Content-MD5 of uploads smaller than a part, which reads each of them twice. Multipart uploads get a CRC32 checksum instead"`
+	MaxRequestsPerSecond     float64                          `mapstructure:"max_requests_per_second" desc:"Maximum amount of requests per second sent to each destination, retries included. Unlimited by default"`
+	Summary                  string                           `mapstructure:"summary" desc:"Path, relative to the target directory, to write a json summary of the deploy to, e.g. deploy-summary.json"`
+	Tracing                  bool                             `mapstructure:"tracing" desc:"Emit OpenTelemetry spans for the deploy and each upload, using the globally configured tracer provider"`
+	DefaultContentType       string                           `mapstructure:"default_content_type" desc:"Content type of the files whose type cannot be detected from their extension, e.g. application/octet-stream"`
+	SyncMetadata             bool                             `mapstructure:"sync_metadata" desc:"With incremental, update the headers and metadata of unchanged objects that differ from the configured ones with a copy instead of uploading them again"`
+	StorageClass             string                           `mapstructure:"storage_class" desc:"Storage class of the uploaded objects. Defaults to the one of the bucket"`
+	StorageClasses           map[string]string                `mapstructure:"storage_classes" desc:"Storage class of the objects matching each glob, overriding storage_class"`
+	StorageClassParallelism  map[string]int                   `mapstructure:"storage_class_parallelism" desc:"Maximum number of parallel uploads of each storage class, on top of max_parallel"`
+	VerifyUploads            bool                             `mapstructure:"verify_uploads" desc:"Check every uploaded object has the size, and when possible the ETag, of the local file"`
+	HTMLLast                 bool                             `mapstructure:"html_last" desc:"Upload html files only once every other file was uploaded, so pages never reference assets that are not there yet"`
+	Removable                *bool                            `mapstructure:"removable" desc:"Whether the target has a remove script. Defaults to true, disable it for append only buckets"`
+	SitemapBaseURL           string                           `mapstructure:"sitemap_base_url" desc:"When set, upload a sitemap.xml listing the html objects, relative to this url which serves the bucket prefix"`
+	KeyTemplate              string                           `mapstructure:"key_template" desc:"Template of the object keys, e.g. releases/${VERSION}/${basename}. ${prefix}, ${relpath}, ${basename} and ${dir} are filled in for every file, other variables are interpolated. Defaults to ${prefix}/${relpath}"`
+	NoOverwrite              bool                             `mapstructure:"no_overwrite" desc:"Fail instead of overwriting objects that already exist, for immutable artifacts"`
+	ContentTypes             map[string]string                `mapstructure:"content_types" desc:"Content type of the files matching each glob, overriding the detected one. Values can reference env vars, e.g. {WASM_CONTENT_TYPE}"`
+	ContentTypesEnv          string                           `mapstructure:"content_types_env" desc:"Name of an env var holding shared content type overrides, as comma separated glob=type pairs. content_types takes precedence"`
+	Region                   string                           `mapstructure:"region" desc:"Region to sign requests for. Defaults to the one of the provider, then AWS_REGION, then us-east-1 with a custom endpoint"`
+	Provider                 string                           `mapstructure:"provider" desc:"S3 compatible provider hosting the bucket, used to pick the region to sign for: aws, cloudflare, digitalocean, gcs, minio or wasabi"`
+	CaptureVersions          bool                             `mapstructure:"capture_versions" desc:"Record the version id of every uploaded object in the deploy manifest. Requires record_manifest, and versioning to be enabled on the bucket"`
+	AdaptiveConcurrency      bool                             `mapstructure:"adaptive_concurrency" desc:"Halve the amount of concurrent uploads whenever S3 responds with SlowDown, retrying the throttled upload, and grow it back as uploads succeed"`
+	FollowSymlinks           bool                             `mapstructure:"follow_symlinks" desc:"Resolve symlinked files with EvalSymlinks to read them, while keeping the key of their logical path"`
+	DatePrefixLayout         string                           `mapstructure:"date_prefix_layout" desc:"Go time layout of a date partition appended to the prefix, e.g. 2006/01/02 for year/month/day"`
+	DatePrefixTimezone       string                           `mapstructure:"date_prefix_timezone" desc:"Timezone the date partition is computed in, e.g. Europe/Berlin. Defaults to UTC"`
+	SoftDeleteTag            string                           `mapstructure:"soft_delete_tag" desc:"Tag, as key=value, that remove marks objects with instead of deleting them, for a lifecycle rule of the bucket to expire them"`
+	RequestPayer             bool                             `mapstructure:"request_payer" desc:"Acknowledge the request charges of requester pays buckets"`
+	SmokeTestURL             string                           `mapstructure:"smoke_test_url" desc:"URL fetched once the deploy is done, failing it unless it answers with a 200. Supports interpolation"`
+	SmokeTestTimeout         string                           `mapstructure:"smoke_test_timeout" desc:"Timeout of the smoke test request. Defaults to 30s"`
+	Anonymous                bool                             `mapstructure:"anonymous" desc:"Send unsigned requests, to read public buckets without any aws credentials"`
+	MaxFileSize              string                           `mapstructure:"max_file_size" desc:"Largest file that can be deployed, e.g. 2GB. Any file above it fails the target before anything is uploaded"`
+	PartConcurrency          *int                             `mapstructure:"part_concurrency" desc:"Maximum number of parts of a single multipart upload sent in parallel, on top of max_parallel files. Defaults to 5"`
+	ReadXattrs               bool                             `mapstructure:"read_xattrs" desc:"Store the extended attributes of each file as object metadata, on platforms supporting them. The user. namespace is stripped from their names"`
+	Xattrs                   []string                         `mapstructure:"xattrs" desc:"Extended attributes read with read_xattrs. Defaults to all of them in the user. namespace"`
+	DownloadDir              string                           `mapstructure:"download_dir" desc:"Directory, relative to the target, the download script writes the objects under the prefix to. Objects not modified since their local copy are skipped. Defaults to download"`
+	ManifestKey              string                           `mapstructure:"manifest_key" desc:"Key, relative to the prefix, record_manifest stores the manifest at. Defaults to .zen-manifest.json"`
+	SkipBrokenSymlinks       bool                             `mapstructure:"skip_broken_symlinks" desc:"Skip outs that are symlinks to missing files, which otherwise fail the deploy"`
+	CABundle                 string                           `mapstructure:"ca_bundle" desc:"Path to a pem file of certificates trusted on top of the system ones, e.g. those of a proxy. Supports interpolation"`
+	HTTPTimeout              string                           `mapstructure:"http_timeout" desc:"Timeout of each http request made to aws, e.g. 30s. Proxies are taken from HTTPS_PROXY and NO_PROXY"`
+	Receipt                  string                           `mapstructure:"receipt" desc:"Path, relative to the target directory, to write a json receipt of the sha256 of every deployed object to. Deploys of the same content have the same receipt checksum"`
+	UploadReceipt            bool                             `mapstructure:"upload_receipt" desc:"Also upload the receipt under the prefix of each destination, as .zen-receipt.json"`
+	ConditionalDelete        bool                             `mapstructure:"conditional_delete" desc:"Only delete objects still holding the etag recorded in the manifest, failing on those that changed since they were deployed. Requires record_manifest"`
+	PresignExpiry            string                           `mapstructure:"presign_expiry" desc:"How long the urls printed by the presign script are valid for, up to 7 days. Defaults to 1h"`
+	ExpectedBucketOwner      string                           `mapstructure:"expected_bucket_owner" desc:"Account id that has to own the buckets, for s3 to reject every request to a bucket of another account"`
+	UploadLast               []string                         `mapstructure:"upload_last" desc:"Globs of the files uploaded only once every other file was, e.g. **/index.html. Combines with html_last"`
+	TempDir                  string                           `mapstructure:"temp_dir" desc:"Directory intermediate files, like compressed uploads, are written to. Defaults to the temp dir of the system"`
+	UseAccelerate            bool                             `mapstructure:"use_accelerate" desc:"Go through the transfer acceleration endpoint of the bucket, which has to have it enabled. Incompatible with path style addressing"`
+	UsePathStyle             *bool                            `mapstructure:"use_path_style" desc:"Address buckets in the path rather than the host name. Defaults to true on aws unless use_accelerate is set, and for custom endpoints to whether their host cannot serve buckets as subdomains, like IPs, single label hosts or hosts with a port"`
+	Rules                    []S3ObjectRule                   `mapstructure:"rules" desc:"Header overrides of the files matching each glob, applied in order over every other object setting, later rules winning"`
+	MaxDeletePercent         int                              `mapstructure:"max_delete_percent" desc:"Largest share, in percent, of the objects under the prefix remove deletes, failing otherwise. Requires record_manifest, as listing the prefix always finds all of them. Disabled by default"`
+	Force                    bool                             `mapstructure:"force" desc:"Remove objects even above max_delete_percent"`
+	Aliases                  []string                         `mapstructure:"aliases" desc:"Prefixes each file is also copied to server side once uploaded, e.g. latest. They start from the bucket root, or from ZEN_S3_ENFORCED_PREFIX when it is set"`
+	GrantRead                string                           `mapstructure:"grant_read" desc:"Grantees given read access to uploaded objects, in the x-amz-grant-read format, e.g. id=\"...\", uri=\"...\""`
+	GrantReadACP             string                           `mapstructure:"grant_read_acp" desc:"Grantees allowed to read the acl of uploaded objects, in the x-amz-grant-read-acp format"`
+	GrantWriteACP            string                           `mapstructure:"grant_write_acp" desc:"Grantees allowed to write the acl of uploaded objects, in the x-amz-grant-write-acp format"`
+	GrantFullControl         string                           `mapstructure:"grant_full_control" desc:"Grantees given full control of uploaded objects, in the x-amz-grant-full-control format. Grants take precedence over inherit_bucket_acl"`
+	Archive                  string                           `mapstructure:"archive" desc:"Pack all the outs into a single archive object instead of uploading them one by one, tar.gz or zip"`
+	ArchiveKey               string                           `mapstructure:"archive_key" desc:"Key (relative to the prefix) the archive is uploaded to. Defaults to the target name followed by the archive extension"`
+	ResumeUploads            bool                             `mapstructure:"resume_uploads" desc:"Keep the parts of failed multipart uploads, and resume them on the next deploy reusing the parts that did not change. Best effort: parts are only reused when uploaded with the same part size and without SSE-KMS, and the resumed object keeps the settings of the interrupted upload"`
 }
 
 func (fc S3FileConfig) GetTargets(tcc *zen_targets.TargetConfigContext) ([]*zen_targets.TargetBuilder, error) {
+	if strings.TrimSpace(fc.Bucket) == "" && len(fc.Destinations) == 0 {
+		return nil, fmt.Errorf("bucket or destinations is required for s3_file target %q", fc.Name)
+	}
+
+	if err := fc.validate(); err != nil {
+		return nil, fmt.Errorf("s3_file target %q: %w", fc.Name, err)
+	}
+
 	if fc.MaxParallel == nil {
 		fc.MaxParallel = new(int)
 		*fc.MaxParallel = 10
 	}
+	if fc.PartConcurrency == nil {
+		fc.PartConcurrency = new(int)
+		*fc.PartConcurrency = manager.DefaultUploadConcurrency
+	}
+
+	if fc.PreflightTimeout == "" {
+		fc.PreflightTimeout = "10s"
+	}
+	if fc.ManifestKey == "" {
+		fc.ManifestKey = manifestName
+	}
+	if fc.PresignExpiry == "" {
+		fc.PresignExpiry = "1h"
+	}
+	if fc.DownloadDir == "" {
+		fc.DownloadDir = "download"
+	}
+	if fc.SmokeTestTimeout == "" {
+		fc.SmokeTestTimeout = "30s"
+	}
+	if fc.Archive != "" && fc.ArchiveKey == "" {
+		fc.ArchiveKey = fc.Name + "." + fc.Archive
+	}
+	if fc.PreflightRetries == nil {
+		fc.PreflightRetries = new(int)
+		*fc.PreflightRetries = 2
+	}
 
 	fc.Labels = append(
 		fc.Labels,
 		fmt.Sprintf("zen_bucket=%s", fc.Bucket),
 		fmt.Sprintf("zen_bucket_prefix=%s", fc.BucketPrefix),
 	)
+	if fc.Tenant != "" {
+		fc.Labels = append(fc.Labels, fmt.Sprintf("zen_tenant=%s", fc.Tenant))
+	}
 
 	t := zen_targets.ToTarget(fc)
 	t.Srcs = map[string][]string{"_srcs": fc.Srcs}
+	if zen_targets.IsTargetReference(fc.Bucket) {
+		// pull the destination from the referenced target
+		t.Srcs[destinationSrc] = []string{fc.Bucket}
+		t.Deps = append(t.Deps, fc.Bucket)
+	}
 	t.Outs = []string{"**/*"}
 
 	t.Scripts["deploy"] = &zen_targets.TargetBuilderScript{
-		Run: func(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) error {
-			target.SetStatus("Uploading to s3 (%s)", target.Qn())
-
-			client, bucket, prefix, err := loadAwsConfig(target)
-			if err != nil {
-				return err
-			}
-
-			// Create an uploader with the S3 client and default options
-			uploader := manager.NewUploader(client)
-
-			// Create a WaitGroup to manage concurrency
-			var wg sync.WaitGroup
-
-			// Create a buffered channel to control concurrency
-			sem := make(chan struct{}, *fc.MaxParallel)
-
-			for _, out := range target.Outs {
-				wg.Add(1)
-
-				// Acquire a token from the semaphore
-				sem <- struct{}{}
-
-				go func(f string) error {
-					// Decrement the counter when the goroutine completes
-					defer wg.Done()
-
-					// Open the file for use
-					file, err := os.Open(f)
-					if err != nil {
-						return fmt.Errorf("failed to open file %q, %v", f, err)
-					}
-					defer file.Close()
-
-					if !runCtx.DryRun {
-						// Use the uploader to upload the file
-						_, err = uploader.Upload(context.TODO(), &s3.PutObjectInput{
-							Bucket: aws.String(bucket),
-							Key:    aws.String(filepath.Join(prefix, strings.TrimPrefix(f, target.Cwd))),
-							Body:   file,
-						})
-						if err != nil {
-							return fmt.Errorf("failed to upload file %q, %v", f, err)
-						}
-
-						target.Debugln("successfully uploaded %q to S3\n", f)
-					}
-					// Release a token back to the semaphore
-					<-sem
-					return nil
-				}(out)
-			}
-
-			// Wait for all uploads to complete
-			wg.Wait()
-
-			return nil
-		},
-	}
-
-	t.Scripts["remove"] = &zen_targets.TargetBuilderScript{
-		Run: func(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) error {
-			client, bucket, prefix, err := loadAwsConfig(target)
-			if err != nil {
-				return err
-			}
-			// Create a WaitGroup to manage concurrency
-			var wg sync.WaitGroup
-
-			// Create a buffered channel to control concurrency
-			sem := make(chan struct{}, *fc.MaxParallel)
-
-			for _, out := range target.Outs {
-				wg.Add(1)
-
-				// Acquire a token from the semaphore
-				sem <- struct{}{}
-
-				go func(f string) {
-					// Decrement the counter when the goroutine completes
-					defer wg.Done()
-
-					// Open the file for use
-					file, err := os.Open(f)
-					if err != nil {
-						log.Fatalf("failed to open file %q, %v", f, err)
-					}
-					defer file.Close()
-
-					if !runCtx.DryRun {
-						input := &s3.DeleteObjectInput{
-							Bucket: aws.String(bucket),
-							Key:    aws.String(filepath.Join(prefix, strings.TrimPrefix(f, target.Cwd))),
-						}
-
-						_, err = client.DeleteObject(context.TODO(), input)
-						if err != nil {
-							log.Fatalf("failed to delete object, %v", err)
-						}
-
-						target.Debugln("successfully deleted %s to S3", f)
-					}
-					// Release a token back to the semaphore
-					<-sem
-				}(out)
-			}
-
-			// Wait for all uploads to complete
-			wg.Wait()
-			return nil
-		},
+		Run: fc.deploy,
+	}
+
+	// Immutable buckets opt out of having a remove script at all
+	if fc.Removable == nil || *fc.Removable {
+		t.Scripts["remove"] = &zen_targets.TargetBuilderScript{
+			Run: fc.remove,
+		}
+	}
+
+	t.Scripts["list"] = &zen_targets.TargetBuilderScript{
+		Run: fc.list,
+	}
+
+	t.Scripts["download"] = &zen_targets.TargetBuilderScript{
+		Run: fc.download,
+	}
+
+	t.Scripts["presign"] = &zen_targets.TargetBuilderScript{
+		Run: fc.presign,
 	}
 
 	return []*zen_targets.TargetBuilder{t}, nil
 }
 
-func loadAwsConfig(target *zen_targets.Target) (*s3.Client, string, string, error) {
-	customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
-		var endpoint string
-		if val, ok := target.Env["AWS_S3_ENDPOINT"]; ok {
-			endpoint = val
-		} else {
-			endpoint = "https://s3.eu-central-1.amazonaws.com"
-		}
-
-		if service == s3.ServiceID && region == "eu-central-1" {
-			return aws.Endpoint{
-				PartitionID:   "aws",
-				URL:           endpoint,
-				SigningRegion: "eu-central-1",
-			}, nil
-		}
-		// returning EndpointNotFoundError will allow the service to fallback to it's default resolution
-		return aws.Endpoint{}, &aws.EndpointNotFoundError{}
-	})
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithEndpointResolverWithOptions(customResolver))
-	if err != nil {
-		return nil, "", "", fmt.Errorf("loading aws config: %w", err)
-	}
-
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		o.UsePathStyle = true
-	})
-
-	var bucket, prefix string
-	for _, label := range target.Labels {
-		if strings.HasPrefix(label, "zen_bucket=") {
-			interpolated, err := target.Interpolate(strings.TrimPrefix(label, "zen_bucket="))
-			if err != nil {
-				return nil, "", "", fmt.Errorf("interpolating bucket name: %w", err)
-			}
-			bucket = interpolated
-		} else if strings.HasPrefix(label, "zen_prefix=") {
-			interpolated, err := target.Interpolate(strings.TrimPrefix(label, "zen_prefix="))
-			if err != nil {
-				return nil, "", "", fmt.Errorf("interpolating bucket key prefix: %w", err)
-			}
-
-			prefix = interpolated
-		}
-	}
-	target.Debugln("Bucket: %s", bucket)
-	target.Debugln("Bucket key: %s", prefix)
-
-	return client, bucket, prefix, nil
+// parallelism returns the amount of concurrent operations to run over n files, never more than there are files
+func (fc S3FileConfig) parallelism(n int) int {
+	if n < 1 {
+		return 1
+	} else if n < *fc.MaxParallel {
+		return n
+	}
+
+	return *fc.MaxParallel
+}
+
+func (fc S3FileConfig) validate() error {
+	if fc.MaxParallel != nil && *fc.MaxParallel < 1 {
+		return fmt.Errorf("max_parallel must be at least 1, got %d", *fc.MaxParallel)
+	}
+	if fc.PartConcurrency != nil && *fc.PartConcurrency < 1 {
+		return fmt.Errorf("part_concurrency must be at least 1, got %d", *fc.PartConcurrency)
+	}
+
+	if fc.VerifyDownloadSampleRate < 0 || fc.VerifyDownloadSampleRate > 1 {
+		return fmt.Errorf("verify_download_sample_rate must be between 0 and 1, got %v", fc.VerifyDownloadSampleRate)
+	}
+
+	if err := validateContentEncoding(fc.ContentEncoding); err != nil {
+		return fmt.Errorf("content_encoding: %w", err)
+	}
+	for pattern, encoding := range fc.ContentEncodings {
+		if err := validateContentEncoding(encoding); err != nil {
+			return fmt.Errorf("content_encodings %q: %w", pattern, err)
+		}
+	}
+	if fc.Compress && fc.ContentEncoding != "" && fc.ContentEncoding != "gzip" {
+		return fmt.Errorf("content_encoding %q conflicts with compress, which uses gzip", fc.ContentEncoding)
+	}
+
+	if fc.ResumeUploads && fc.SendContentMD5 {
+		return fmt.Errorf("resume_uploads can not be combined with send_content_md5, resumed parts carry no checksum")
+	}
+	if fc.ResumeUploads && fc.NoOverwrite {
+		return fmt.Errorf("resume_uploads can not be combined with no_overwrite, resumed uploads are not conditional")
+	}
+
+	if fc.Archive != "" {
+		if _, ok := archiveContentTypes[fc.Archive]; !ok {
+			return fmt.Errorf("archive must be one of %s or %s, got %q", archiveTarGz, archiveZip, fc.Archive)
+		}
+		if fc.Compress {
+			return fmt.Errorf("compress can not be combined with archive, which is already compressed")
+		}
+	}
+
+	if fc.MaxInflightBytes != "" {
+		if size, err := parseByteSize(fc.MaxInflightBytes); err != nil {
+			return fmt.Errorf("max_inflight_bytes: %w", err)
+		} else if size < 1 {
+			return fmt.Errorf("max_inflight_bytes must be positive, got %q", fc.MaxInflightBytes)
+		}
+	}
+	if fc.MaxFileSize != "" {
+		if size, err := parseByteSize(fc.MaxFileSize); err != nil {
+			return fmt.Errorf("max_file_size: %w", err)
+		} else if size < 1 {
+			return fmt.Errorf("max_file_size must be positive, got %q", fc.MaxFileSize)
+		}
+	}
+
+	if fc.VersionPointer != "" && fc.Version == "" {
+		return fmt.Errorf("version_pointer requires version")
+	} else if fc.Version != "" && fc.VersionPointer == "" {
+		return fmt.Errorf("version requires version_pointer")
+	}
+
+	if fc.PreflightTimeout != "" {
+		if timeout, err := time.ParseDuration(fc.PreflightTimeout); err != nil {
+			return fmt.Errorf("preflight_timeout: %w", err)
+		} else if timeout <= 0 {
+			return fmt.Errorf("preflight_timeout must be positive, got %q", fc.PreflightTimeout)
+		}
+	}
+	if fc.HTTPTimeout != "" {
+		if timeout, err := time.ParseDuration(fc.HTTPTimeout); err != nil {
+			return fmt.Errorf("http_timeout: %w", err)
+		} else if timeout <= 0 {
+			return fmt.Errorf("http_timeout must be positive, got %q", fc.HTTPTimeout)
+		}
+	}
+	if fc.PresignExpiry != "" {
+		if expiry, err := time.ParseDuration(fc.PresignExpiry); err != nil {
+			return fmt.Errorf("presign_expiry: %w", err)
+		} else if expiry <= 0 || expiry > 7*24*time.Hour {
+			return fmt.Errorf("presign_expiry must be positive and at most 7 days, got %q", fc.PresignExpiry)
+		}
+	}
+	if fc.SmokeTestTimeout != "" {
+		if timeout, err := time.ParseDuration(fc.SmokeTestTimeout); err != nil {
+			return fmt.Errorf("smoke_test_timeout: %w", err)
+		} else if timeout <= 0 {
+			return fmt.Errorf("smoke_test_timeout must be positive, got %q", fc.SmokeTestTimeout)
+		}
+	}
+	if fc.PreflightRetries != nil && *fc.PreflightRetries < 0 {
+		return fmt.Errorf("preflight_retries cannot be negative, got %d", *fc.PreflightRetries)
+	}
+
+	for pattern, location := range fc.Redirects {
+		if err := validateRedirect(location); err != nil {
+			return fmt.Errorf("redirects %q: %w", pattern, err)
+		}
+	}
+
+	for i, dest := range fc.Destinations {
+		if strings.TrimSpace(dest.Bucket) == "" {
+			return fmt.Errorf("destination %d: bucket is required", i)
+		}
+	}
+
+	if fc.MaxRequestsPerSecond < 0 {
+		return fmt.Errorf("max_requests_per_second cannot be negative, got %v", fc.MaxRequestsPerSecond)
+	}
+
+	if fc.StorageClass != "" {
+		if err := validateStorageClass(fc.StorageClass); err != nil {
+			return fmt.Errorf("storage_class: %w", err)
+		}
+	}
+	for pattern, class := range fc.StorageClasses {
+		if err := validateStorageClass(class); err != nil {
+			return fmt.Errorf("storage_classes %q: %w", pattern, err)
+		}
+	}
+	for class, n := range fc.StorageClassParallelism {
+		if err := validateStorageClass(class); err != nil {
+			return fmt.Errorf("storage_class_parallelism: %w", err)
+		} else if n < 1 {
+			return fmt.Errorf("storage_class_parallelism of %s must be at least 1, got %d", class, n)
+		}
+	}
+
+	if fc.SitemapBaseURL != "" {
+		if u, err := url.Parse(fc.SitemapBaseURL); err != nil {
+			return fmt.Errorf("sitemap_base_url: %w", err)
+		} else if !u.IsAbs() {
+			return fmt.Errorf("sitemap_base_url must be an absolute url, got %q", fc.SitemapBaseURL)
+		}
+	}
+
+	if fc.KeyTemplate != "" && fc.Flatten {
+		return fmt.Errorf("key_template and flatten cannot be used together")
+	}
+
+	if _, ok := providerRegions[fc.Provider]; fc.Provider != "" && !ok {
+		providers := maps.Keys(providerRegions)
+		slices.Sort(providers)
+		return fmt.Errorf("provider must be one of %s, got %q", strings.Join(providers, ", "), fc.Provider)
+	}
+
+	if fc.DatePrefixTimezone != "" {
+		if fc.DatePrefixLayout == "" {
+			return fmt.Errorf("date_prefix_timezone requires date_prefix_layout")
+		} else if _, err := time.LoadLocation(fc.DatePrefixTimezone); err != nil {
+			return fmt.Errorf("date_prefix_timezone: %w", err)
+		}
+	}
+
+	if key, _, ok := strings.Cut(fc.SoftDeleteTag, "="); fc.SoftDeleteTag != "" && (!ok || key == "") {
+		return fmt.Errorf("soft_delete_tag must be formatted as key=value, got %q", fc.SoftDeleteTag)
+	}
+
+	if fc.ExpectedBucketOwner != "" && (len(fc.ExpectedBucketOwner) != 12 || strings.Trim(fc.ExpectedBucketOwner, "0123456789") != "") {
+		return fmt.Errorf("expected_bucket_owner has to be a 12 digit account id, got %q", fc.ExpectedBucketOwner)
+	}
+	for _, alias := range fc.Aliases {
+		if strings.Trim(alias, "/") == "" {
+			return fmt.Errorf("aliases cannot be empty, it would copy the files to the bucket root")
+		}
+	}
+	if fc.MaxDeletePercent < 0 || fc.MaxDeletePercent > 100 {
+		return fmt.Errorf("max_delete_percent must be between 0 and 100, got %d", fc.MaxDeletePercent)
+	} else if fc.MaxDeletePercent > 0 && !fc.RecordManifest {
+		return fmt.Errorf("max_delete_percent requires record_manifest, without it remove deletes everything under the prefix")
+	}
+	for i, rule := range fc.Rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	if fc.UseAccelerate && fc.UsePathStyle != nil && *fc.UsePathStyle {
+		return fmt.Errorf("use_accelerate cannot be set together with use_path_style, acceleration needs the bucket in the host name")
+	} else if fc.UseAccelerate && fc.Endpoint != "" {
+		return fmt.Errorf("use_accelerate cannot be set together with endpoint, it only exists on aws")
+	}
+	if fc.CaptureVersions && !fc.RecordManifest {
+		return fmt.Errorf("capture_versions requires record_manifest, where the versions are recorded")
+	}
+	if fc.ConditionalDelete && !fc.RecordManifest {
+		return fmt.Errorf("conditional_delete requires record_manifest, where the etags are read from")
+	} else if fc.ConditionalDelete && fc.SoftDeleteTag != "" {
+		return fmt.Errorf("conditional_delete cannot be set together with soft_delete_tag")
+	}
+	if fc.ManifestKey != "" && strings.HasSuffix(fc.ManifestKey, "/") {
+		return fmt.Errorf("manifest_key has to name an object, got %q", fc.ManifestKey)
+	}
+
+	switch fc.KeyCase {
+	case "", keyCasePreserve, keyCaseLower, keyCaseUpper:
+	default:
+		return fmt.Errorf("key_case must be one of %s, %s or %s, got %q", keyCasePreserve, keyCaseLower, keyCaseUpper, fc.KeyCase)
+	}
+
+	if fc.ACL != "" && !slices.Contains(types.ObjectCannedACL("").Values(), types.ObjectCannedACL(fc.ACL)) {
+		return fmt.Errorf("acl must be one of %v, got %q", types.ObjectCannedACL("").Values(), fc.ACL)
+	}
+
+	if fc.ACL != "" && !fc.grants().empty() {
+		return fmt.Errorf("acl can not be combined with grant_read, grant_read_acp, grant_write_acp or grant_full_control")
+	}
+
+	if (fc.AccessKeyID == "") != (fc.SecretAccessKey == "") {
+		return fmt.Errorf("access_key_id and secret_access_key have to be set together")
+	}
+	if fc.Anonymous && fc.AccessKeyID != "" {
+		return fmt.Errorf("anonymous cannot be set together with access_key_id")
+	}
+
+	switch types.ObjectLockMode(fc.ObjectLockMode) {
+	case "":
+		if fc.ObjectLockRetainUntil != "" {
+			return fmt.Errorf("object_lock_retain_until requires object_lock_mode")
+		}
+	case types.ObjectLockModeGovernance, types.ObjectLockModeCompliance:
+		if fc.ObjectLockRetainUntil == "" {
+			return fmt.Errorf("object_lock_mode requires object_lock_retain_until")
+		}
+	default:
+		return fmt.Errorf("object_lock_mode must be one of %s or %s, got %q", types.ObjectLockModeGovernance, types.ObjectLockModeCompliance, fc.ObjectLockMode)
+	}
+
+	switch types.ServerSideEncryption(fc.SSE) {
+	case "", types.ServerSideEncryptionAes256, types.ServerSideEncryptionAwsKms:
+	default:
+		return fmt.Errorf("sse must be one of %s or %s, got %q", types.ServerSideEncryptionAes256, types.ServerSideEncryptionAwsKms, fc.SSE)
+	}
+
+	if fc.SSE != string(types.ServerSideEncryptionAwsKms) {
+		if fc.SSEKMSKeyID != "" {
+			return fmt.Errorf("sse_kms_key_id requires sse to be %s", types.ServerSideEncryptionAwsKms)
+		}
+		if fc.BucketKeyEnabled != nil {
+			return fmt.Errorf("bucket_key_enabled requires sse to be %s", types.ServerSideEncryptionAwsKms)
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,83 @@
+package s3
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestMaxParallelBounds(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		fc := testConfig("site")
+		fc.MaxParallel = intPtr(n)
+		if _, err := fc.GetTargets(&zen_targets.TargetConfigContext{}); err == nil || !strings.Contains(err.Error(), "max_parallel") {
+			t.Errorf("max_parallel %d: got %v, want a configuration error", n, err)
+		}
+	}
+
+	fc := testConfig("site")
+	fc.MaxParallel = intPtr(1000)
+	if _, err := fc.GetTargets(&zen_targets.TargetConfigContext{}); err != nil {
+		t.Fatalf("max_parallel 1000: %v", err)
+	}
+	if got := fc.parallelism(3); got != 3 {
+		t.Errorf("got %d workers for 3 files, want 3", got)
+	}
+	if got := fc.parallelism(0); got != 1 {
+		t.Errorf("got %d workers without files, want 1", got)
+	}
+
+	fc.MaxParallel = intPtr(2)
+	if got := fc.parallelism(3); got != 2 {
+		t.Errorf("got %d workers with max_parallel 2, want 2", got)
+	}
+}
+
+func TestMissingBucketFailsFast(t *testing.T) {
+	for _, bucket := range []string{"", "  "} {
+		fc := testConfig("site")
+		fc.Bucket = bucket
+		if _, err := fc.GetTargets(&zen_targets.TargetConfigContext{}); err == nil || !strings.Contains(err.Error(), "bucket or destinations is required") {
+			t.Errorf("bucket %q: got %v, want a configuration error", bucket, err)
+		}
+	}
+
+	fake := newFakeS3()
+	useFake(t, fake)
+	fc := testConfig("site")
+	fc.Bucket = "{BUCKET}"
+	target := testTarget(t, fc, map[string]string{"a.txt": "a"})
+	target.Env["BUCKET"] = ""
+
+	if err := runScript(t, fc, "deploy", target, nil); err == nil || !strings.Contains(err.Error(), "bucket is required") {
+		t.Errorf("got %v, want the empty bucket to be reported", err)
+	}
+	if n := len(fake.calls); n != 0 {
+		t.Errorf("made %d calls with an empty bucket", n)
+	}
+}
+
+func TestRemovable(t *testing.T) {
+	for name, removable := range map[string]*bool{"unset": nil, "true": aws.Bool(true), "false": aws.Bool(false)} {
+		fc := testConfig("site")
+		fc.Removable = removable
+		targets, err := fc.GetTargets(&zen_targets.TargetConfigContext{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := removable == nil || *removable
+		if _, ok := targets[0].Scripts["remove"]; ok != want {
+			t.Errorf("removable %s: got a remove script %t, want %t", name, ok, want)
+		}
+		if _, ok := targets[0].Scripts["deploy"]; !ok {
+			t.Errorf("removable %s: no deploy script", name)
+		}
+	}
+}
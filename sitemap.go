@@ -0,0 +1,67 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sitemapName is the object, under the prefix, the sitemap is uploaded to
+const sitemapName = "sitemap.xml"
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+// sitemap lists the url of every html object uploaded from outs, the base url being where the prefix is served from
+func (d *deployment) sitemap(outs []string) ([]byte, error) {
+	base, err := url.Parse(d.fc.SitemapBaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing sitemap base url: %w", err)
+	}
+
+	set := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	d.eachUploadJob(outs, func(job uploadJob) bool {
+		if strings.HasPrefix(d.fc.contentType(job.rel), "text/html") {
+			set.URLs = append(set.URLs, sitemapURL{Loc: base.JoinPath(d.sitemapPath(job)).String()})
+		}
+		return true
+	})
+
+	body, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding sitemap: %w", err)
+	}
+
+	return append([]byte(xml.Header), body...), nil
+}
+
+// sitemapPath is the path of the object of job under the prefix, with key_case applied like to the key itself.
+// Files go through objectKey without a prefix, so flatten and the key template are applied too.
+func (d *deployment) sitemapPath(job uploadJob) string {
+	if job.file != "" {
+		return d.fc.objectKey("", d.target.Cwd, job.file)
+	}
+	return applyKeyCase(d.fc.KeyCase, job.rel)
+}
+
+// uploadSitemap uploads the sitemap of the html objects of the deploy, returning its key when it was written
+func (d *deployment) uploadSitemap(ctx context.Context, outs []string) (string, error) {
+	sitemap, err := d.sitemap(outs)
+	if err != nil {
+		return "", err
+	}
+
+	body := bytes.NewReader(sitemap)
+	return d.send(ctx, d.contentKey(sitemapName), sitemapName, body, body.Size(), time.Time{}, nil)
+}
@@ -0,0 +1,73 @@
+package s3
+
+import (
+	"encoding/xml"
+	"mime"
+	"sort"
+	"testing"
+)
+
+func TestSitemapListsHTMLObjects(t *testing.T) {
+	fc := testConfig("site")
+	fc.SitemapBaseURL = "https://example.com/docs/"
+
+	fake, _ := deployFiles(t, fc, map[string]string{
+		"index.html":       "<html></html>",
+		"blog/first.html":  "<html></html>",
+		"blog/second.html": "<html></html>",
+		"app.js":           "app",
+	})
+
+	obj := fake.object("site/" + sitemapName)
+	if obj == nil {
+		t.Fatalf("no sitemap uploaded, got %v", fake.stored())
+	}
+	if want := mime.TypeByExtension(".xml"); obj.contentType != want {
+		t.Errorf("sitemap content type %q, want %q", obj.contentType, want)
+	}
+
+	var set sitemapURLSet
+	if err := xml.Unmarshal(obj.body, &set); err != nil {
+		t.Fatalf("decoding sitemap %s: %v", obj.body, err)
+	}
+
+	got := []string{}
+	for _, u := range set.URLs {
+		got = append(got, u.Loc)
+	}
+	sort.Strings(got)
+	want := []string{
+		"https://example.com/docs/blog/first.html",
+		"https://example.com/docs/blog/second.html",
+		"https://example.com/docs/index.html",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("sitemap urls %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sitemap urls %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestSitemapWithKeyCase(t *testing.T) {
+	fc := testConfig("Site")
+	fc.KeyCase = keyCaseLower
+	fc.SitemapBaseURL = "https://example.com/"
+
+	fake, _ := deployFiles(t, fc, map[string]string{"Blog/First.html": "<html></html>"})
+
+	obj := fake.object("site/" + sitemapName)
+	if obj == nil {
+		t.Fatalf("no sitemap uploaded, got %v", fake.stored())
+	}
+	var set sitemapURLSet
+	if err := xml.Unmarshal(obj.body, &set); err != nil {
+		t.Fatalf("decoding sitemap %s: %v", obj.body, err)
+	}
+	if len(set.URLs) != 1 || set.URLs[0].Loc != "https://example.com/blog/first.html" {
+		t.Errorf("sitemap urls %v, want the lower cased path under the prefix", set.URLs)
+	}
+}
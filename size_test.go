@@ -0,0 +1,124 @@
+package s3
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestByteBudgetBlocksWhenExceeded(t *testing.T) {
+	b := newByteBudget(10)
+	b.acquire(6)
+
+	acquired := make(chan struct{})
+	go func() {
+		b.acquire(6)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired 12 bytes of a 10 bytes budget")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	b.release(6)
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("still blocked once the budget was released")
+	}
+
+	// a file larger than the whole budget goes through once nothing else is in flight
+	b.release(6)
+	b.acquire(20)
+}
+
+func TestMaxInflightBytesThrottlesDispatch(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	fake.hook = func(ctx context.Context, op, key string) error {
+		if op != "PutObject" {
+			return nil
+		}
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}
+
+	fc := testConfig("site")
+	fc.MaxInflightBytes = "10B"
+	target := testTarget(t, fc, map[string]string{"a.txt": "aaaaaa", "b.txt": "bbbbbb", "c.txt": "cccccc"})
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	if maxRunning != 1 {
+		t.Errorf("ran %d uploads of 6 bytes at once with a budget of 10 bytes", maxRunning)
+	}
+	if n := fake.count("PutObject"); n != 3 {
+		t.Errorf("made %d uploads, want 3", n)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	for s, want := range map[string]int64{
+		"512":     512,
+		"10B":     10,
+		"2KB":     2000,
+		"2kib":    2048,
+		"1.5GiB":  3 << 29,
+		"2 GB":    2000 * 1000 * 1000,
+		" 100MB ": 100 * 1000 * 1000,
+	} {
+		if got, err := parseByteSize(s); err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v, want %d", s, got, err, want)
+		}
+	}
+
+	for _, s := range []string{"", "GB", "10XB", "-1MB", "1..5MB"} {
+		if _, err := parseByteSize(s); err == nil {
+			t.Errorf("parseByteSize(%q) succeeded", s)
+		}
+	}
+}
+
+func TestMaxFileSize(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.MaxFileSize = "1KB"
+	target := testTarget(t, fc, map[string]string{"small.txt": "small", "big.bin": strings.Repeat("x", 1001)})
+
+	err := runScript(t, fc, "deploy", target, nil)
+	if err == nil || !strings.Contains(err.Error(), "big.bin (1001 bytes)") || strings.Contains(err.Error(), "small.txt") {
+		t.Fatalf("got %v, want big.bin reported", err)
+	}
+	if n := fake.count("PutObject"); n != 0 {
+		t.Errorf("uploaded %d files before failing", n)
+	}
+
+	fc.MaxFileSize = "2KB"
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Errorf("deploy under the limit: %v", err)
+	}
+	if n := fake.count("PutObject"); n != 2 {
+		t.Errorf("uploaded %d files under the limit, want 2", n)
+	}
+}
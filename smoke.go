@@ -0,0 +1,48 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+// smokeTest fetches the configured url once the deploy is done, failing unless it answers with a 200
+func (fc S3FileConfig) smokeTest(target *zen_targets.Target, runCtx *zen_targets.RuntimeContext) error {
+	url, err := interpolateAtRuntime(target, runCtx, fc.SmokeTestURL)
+	if err != nil {
+		return fmt.Errorf("interpolating smoke test url: %w", err)
+	}
+
+	if runCtx.DryRun {
+		target.Infoln("smoke test GET %s", url)
+		return nil
+	}
+
+	timeout, _ := time.ParseDuration(fc.SmokeTestTimeout)
+	ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating smoke test request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("smoke test of %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	// drain the body so the connection can be reused
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("smoke test of %s: expected status 200, got %d", url, resp.StatusCode)
+	}
+
+	target.Debugln("smoke test of %s passed", url)
+	return nil
+}
@@ -0,0 +1,56 @@
+package s3
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+func TestSmokeTest(t *testing.T) {
+	var status atomic.Int32
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/site/index.html" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.SmokeTestURL = "{SITE_URL}/site/index.html"
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+	target.Env["SITE_URL"] = server.URL
+
+	status.Store(http.StatusOK)
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Errorf("deploy with a healthy site: %v", err)
+	}
+
+	status.Store(http.StatusForbidden)
+	if err := runScript(t, fc, "deploy", target, nil); err == nil || !strings.Contains(err.Error(), "expected status 200, got 403") {
+		t.Errorf("got %v, want the failed smoke test to fail the deploy", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("made %d smoke test requests, want 2", n)
+	}
+
+	if err := runScript(t, fc, "deploy", target, &zen_targets.RuntimeContext{DryRun: true}); err != nil {
+		t.Errorf("dry run: %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Error("the dry run made a smoke test request")
+	}
+	if !logged(target, "smoke test GET "+server.URL+"/site/index.html") {
+		t.Errorf("the dry run did not log the smoke test, got %q", target.Logs)
+	}
+}
@@ -0,0 +1,64 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	zen_targets "github.com/zen-io/zen-core/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// softDeleteTag splits the key=value tag objects are marked with instead of being deleted
+func (fc S3FileConfig) softDeleteTag() types.Tag {
+	key, val, _ := strings.Cut(fc.SoftDeleteTag, "=")
+	return types.Tag{Key: aws.String(key), Value: aws.String(val)}
+}
+
+// tagBatch marks the objects with the soft delete tag, for a lifecycle rule of the bucket to expire them.
+// The existing tags of the objects are kept. Tagged objects are reported to onDeleted, as they are as good as deleted.
+func (fc S3FileConfig) tagBatch(ctx context.Context, target *zen_targets.Target, client s3API, bucket string, batch []remoteObject, onDeleted func(ObjectEvent)) []error {
+	tag := fc.softDeleteTag()
+
+	errs := []error{}
+	for _, obj := range batch {
+		start := time.Now()
+		existing, err := client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(obj.key),
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to read the tags of %q, %v", obj.key, err)
+			notifyDeleted(onDeleted, bucket, []remoteObject{obj}, time.Since(start), nil, err)
+			errs = append(errs, err)
+			continue
+		}
+
+		tags := []types.Tag{tag}
+		for _, t := range existing.TagSet {
+			if aws.ToString(t.Key) != aws.ToString(tag.Key) {
+				tags = append(tags, t)
+			}
+		}
+
+		if _, err := client.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+			Bucket:  aws.String(bucket),
+			Key:     aws.String(obj.key),
+			Tagging: &types.Tagging{TagSet: tags},
+		}); err != nil {
+			err = fmt.Errorf("failed to tag %q, %v", obj.key, err)
+			notifyDeleted(onDeleted, bucket, []remoteObject{obj}, time.Since(start), nil, err)
+			errs = append(errs, err)
+			continue
+		}
+		notifyDeleted(onDeleted, bucket, []remoteObject{obj}, time.Since(start), nil, nil)
+
+		target.Debugln("s3://%s/%s: tagged %s for expiration", bucket, obj.key, fc.SoftDeleteTag)
+	}
+
+	return errs
+}
@@ -0,0 +1,75 @@
+package s3
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func tagMap(tags []types.Tag) map[string]string {
+	m := map[string]string{}
+	for _, tag := range tags {
+		m[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return m
+}
+
+func TestSoftDeleteTagsInsteadOfDeleting(t *testing.T) {
+	fc := testConfig("site")
+	fc.SoftDeleteTag = "expire=true"
+	fake, target := deployFiles(t, fc, map[string]string{"index.html": "<html></html>", "app.js": "app"})
+	fake.object("site/app.js").tags = []types.Tag{
+		{Key: aws.String("team"), Value: aws.String("web")},
+		{Key: aws.String("expire"), Value: aws.String("false")},
+	}
+
+	if err := runScript(t, fc, "remove", target, nil); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	if n := fake.count("DeleteObject") + fake.count("DeleteObjects"); n != 0 {
+		t.Errorf("made %d delete calls, want the objects tagged instead", n)
+	}
+	for _, key := range []string{"site/index.html", "site/app.js"} {
+		obj := fake.object(key)
+		if obj == nil {
+			t.Fatalf("%s was deleted", key)
+		}
+		if tags := tagMap(obj.tags); tags["expire"] != "true" {
+			t.Errorf("%s tagged %v, want expire=true", key, tags)
+		}
+	}
+
+	if tags := tagMap(fake.object("site/app.js").tags); len(tags) != 2 || tags["team"] != "web" {
+		t.Errorf("site/app.js tagged %v, want its other tags kept", tags)
+	}
+}
+
+func TestSoftDeletesAreReported(t *testing.T) {
+	fc := testConfig("site")
+	fc.SoftDeleteTag = "expire=true"
+	fake, target := deployFiles(t, fc, map[string]string{"index.html": "<html></html>", "app.js": "app"})
+	fake.fail("PutObjectTagging", "site/app.js", accessDeniedError())
+
+	var mu sync.Mutex
+	events := map[string]error{}
+	fc.UploadOptions = &UploadOptions{
+		OnDeleted: func(event ObjectEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events[event.Key] = event.Err
+		},
+	}
+
+	if err := runScript(t, fc, "remove", target, nil); err == nil {
+		t.Fatal("remove succeeded, want the failed tagging reported")
+	}
+	if err, ok := events["site/index.html"]; !ok || err != nil {
+		t.Errorf("got %v for the tagged object, want a successful delete event", events)
+	}
+	if err := events["site/app.js"]; err == nil {
+		t.Errorf("got %v for the object failing to be tagged, want its error", events)
+	}
+}
@@ -0,0 +1,44 @@
+package s3
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"golang.org/x/exp/slices"
+)
+
+// storageClass returns the storage class of the object at rel, empty for the bucket default
+func (fc S3FileConfig) storageClass(rel string) string {
+	if class, ok := matchGlobValue(fc.StorageClasses, rel); ok {
+		return class
+	}
+
+	return fc.StorageClass
+}
+
+// objectStorageClass returns the storage class the object at rel is uploaded with, once the rules are applied
+func (fc S3FileConfig) objectStorageClass(rel string) string {
+	input := &s3.PutObjectInput{StorageClass: types.StorageClass(fc.storageClass(rel))}
+	fc.applyRules(input, rel)
+
+	return string(input.StorageClass)
+}
+
+func validateStorageClass(class string) error {
+	if !slices.Contains(types.StorageClass("").Values(), types.StorageClass(class)) {
+		return fmt.Errorf("storage class must be one of %v, got %q", types.StorageClass("").Values(), class)
+	}
+
+	return nil
+}
+
+// classSemaphores creates a semaphore for every storage class with its own parallelism
+func (fc S3FileConfig) classSemaphores() map[string]chan struct{} {
+	sems := map[string]chan struct{}{}
+	for class, n := range fc.StorageClassParallelism {
+		sems[class] = make(chan struct{}, n)
+	}
+
+	return sems
+}
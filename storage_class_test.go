@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStorageClassParallelism(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	var mu sync.Mutex
+	archived, maxArchived := 0, 0
+	standard := make(chan string, 3)
+	release := make(chan struct{})
+	fake.hook = func(ctx context.Context, op, key string) error {
+		if op != "PutObject" {
+			return nil
+		}
+		if !strings.HasSuffix(key, ".bin") {
+			standard <- key
+			return nil
+		}
+
+		mu.Lock()
+		archived++
+		if archived > maxArchived {
+			maxArchived = archived
+		}
+		mu.Unlock()
+
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+
+		mu.Lock()
+		archived--
+		mu.Unlock()
+		return nil
+	}
+
+	fc := testConfig("site")
+	fc.MaxParallel = new(int)
+	*fc.MaxParallel = 4
+	// the class is only set by a rule
+	fc.Rules = []S3ObjectRule{{Match: "*.bin", StorageClass: "GLACIER"}}
+	fc.StorageClassParallelism = map[string]int{"GLACIER": 1}
+	target := testTarget(t, fc, map[string]string{
+		"a1.bin": "a", "a2.bin": "a",
+		"s1.txt": "s", "s2.txt": "s", "s3.txt": "s",
+	})
+
+	done := make(chan error)
+	go func() { done <- runScript(t, fc, "deploy", target, nil) }()
+
+	// the standard files go through while the archived ones wait for their slot
+	for i := 0; i < 3; i++ {
+		select {
+		case <-standard:
+		case <-time.After(5 * time.Second):
+			t.Fatal("standard uploads were held up behind the storage class limit")
+		}
+	}
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if maxArchived != 1 {
+		t.Errorf("uploaded %d GLACIER objects at once, want 1", maxArchived)
+	}
+	if got := fake.object("site/a1.bin").storageClass; got != "GLACIER" {
+		t.Errorf("site/a1.bin has storage class %q", got)
+	}
+}
@@ -0,0 +1,99 @@
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	summaryUploaded = "uploaded"
+	summarySkipped  = "skipped"
+	summaryFailed   = "failed"
+)
+
+// deploySummary is the machine readable outcome of a deploy, written to the summary file
+type deploySummary struct {
+	mu sync.Mutex
+
+	Uploaded int           `json:"uploaded"`
+	Skipped  int           `json:"skipped"`
+	Failed   int           `json:"failed"`
+	Bytes    int64         `json:"bytes"`
+	Files    []fileSummary `json:"files"`
+}
+
+// fileSummary is the outcome of a single upload
+type fileSummary struct {
+	Destination string `json:"destination"`
+	Key         string `json:"key"`
+	File        string `json:"file,omitempty"`
+	Size        int64  `json:"size"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// uploadStatus is the status of an upload that returned the key and error
+func uploadStatus(key string, err error) string {
+	switch {
+	case err != nil:
+		return summaryFailed
+	case key == "":
+		return summarySkipped
+	default:
+		return summaryUploaded
+	}
+}
+
+// record adds the outcome of an upload, uploaded being the key returned by it. It does nothing on a nil summary.
+func (s *deploySummary) record(dest destination, job uploadJob, uploaded string, err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file := fileSummary{
+		Destination: dest.String(),
+		Key:         job.key,
+		File:        job.file,
+		Size:        job.size,
+	}
+	file.Status = uploadStatus(uploaded, err)
+	switch file.Status {
+	case summaryFailed:
+		file.Error = err.Error()
+		s.Failed++
+	case summarySkipped:
+		s.Skipped++
+	default:
+		s.Uploaded++
+		s.Bytes += job.size
+	}
+
+	s.Files = append(s.Files, file)
+}
+
+// write stores the summary at p, relative to the target directory
+func (s *deploySummary) write(cwd, p string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(cwd, p)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding deploy summary: %w", err)
+	}
+
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		return fmt.Errorf("writing deploy summary to %s: %w", p, err)
+	}
+
+	return nil
+}
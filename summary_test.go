@@ -0,0 +1,65 @@
+package s3
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSummaryCountsUploadedAndSkipped(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.Incremental = true
+	fc.Summary = "deploy-summary.json"
+	target := testTarget(t, fc, map[string]string{
+		"index.html": "<html></html>",
+		"app.js":     "app",
+		"style.css":  "style",
+		"logo.svg":   "<svg></svg>",
+	})
+
+	// unchanged since the last deploy
+	fake.put("site/logo.svg", "<svg></svg>")
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(target.Cwd, "deploy-summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary deploySummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+
+	if summary.Uploaded != 3 || summary.Skipped != 1 || summary.Failed != 0 {
+		t.Errorf("uploaded %d, skipped %d, failed %d, want 3, 1 and 0", summary.Uploaded, summary.Skipped, summary.Failed)
+	}
+	if want := int64(len("<html></html>") + len("app") + len("style")); summary.Bytes != want {
+		t.Errorf("bytes %d, want %d", summary.Bytes, want)
+	}
+
+	statuses := map[string]string{}
+	for _, f := range summary.Files {
+		statuses[f.Key] = f.Status
+	}
+	want := map[string]string{
+		"site/index.html": summaryUploaded,
+		"site/app.js":     summaryUploaded,
+		"site/style.css":  summaryUploaded,
+		"site/logo.svg":   summarySkipped,
+	}
+	for key, status := range want {
+		if statuses[key] != status {
+			t.Errorf("%s is %q, want %q", key, statuses[key], status)
+		}
+	}
+	if len(summary.Files) != len(want) {
+		t.Errorf("summary lists %d files, want %d", len(summary.Files), len(want))
+	}
+}
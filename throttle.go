@@ -0,0 +1,97 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+// maxThrottledAttempts is how many times an upload throttled with SlowDown is tried before giving up
+const maxThrottledAttempts = 4
+
+// adaptiveLimiter bounds the amount of concurrent uploads, halving the bound when S3 asks to slow down
+// and growing it back by one after as many successful uploads as the current bound
+type adaptiveLimiter struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	limit     int
+	max       int
+	inUse     int
+	successes int
+}
+
+func newAdaptiveLimiter(max int) *adaptiveLimiter {
+	l := &adaptiveLimiter{limit: max, max: max}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *adaptiveLimiter) acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.inUse >= l.limit {
+		l.cond.Wait()
+	}
+	l.inUse++
+}
+
+// release frees a slot, adapting the bound to whether the upload was throttled. It returns the new bound.
+func (l *adaptiveLimiter) release(throttled bool) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inUse--
+	if throttled {
+		l.limit = l.limit / 2
+		if l.limit < 1 {
+			l.limit = 1
+		}
+		l.successes = 0
+	} else if l.limit < l.max {
+		l.successes++
+		if l.successes >= l.limit {
+			l.limit++
+			l.successes = 0
+		}
+	}
+
+	l.cond.Broadcast()
+	return l.limit
+}
+
+// isSlowDown checks whether S3 rejected a request because of the request rate
+func isSlowDown(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "SlowDown" {
+		return true
+	}
+
+	var respErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusServiceUnavailable
+}
+
+// runThrottled runs the upload within the limiter, trying again with a growing backoff while it is throttled
+func (d *deployment) runThrottled(ctx context.Context, limiter *adaptiveLimiter, job uploadJob) (string, error) {
+	for attempt := 1; ; attempt++ {
+		limiter.acquire()
+		key, err := job.run(ctx)
+		throttled := isSlowDown(err)
+		limit := limiter.release(throttled)
+
+		if !throttled || attempt == maxThrottledAttempts {
+			return key, err
+		}
+
+		d.debugln(job.key, "throttled, retrying with at most %d concurrent uploads", limit)
+		select {
+		case <-ctx.Done():
+			return "", err
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	l := newAdaptiveLimiter(4)
+	for i := 0; i < 4; i++ {
+		l.acquire()
+	}
+
+	if limit := l.release(true); limit != 2 {
+		t.Errorf("got limit %d after slowing down, want 2", limit)
+	}
+
+	// the 3 uploads still running are over the limit, so no new one starts until two of them are done
+	acquired := make(chan struct{})
+	go func() {
+		l.acquire()
+		close(acquired)
+	}()
+	l.release(false)
+	select {
+	case <-acquired:
+		t.Fatal("acquired a slot over the reduced limit")
+	case <-time.After(20 * time.Millisecond):
+	}
+	l.release(false)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("did not acquire a slot once under the limit")
+	}
+
+	if limit := l.release(true); limit != 1 {
+		t.Errorf("got limit %d after slowing down again, want 1", limit)
+	}
+	if limit := l.release(true); limit != 1 {
+		t.Errorf("got limit %d, want it to stay at 1", limit)
+	}
+
+	// it grows back by one after as many successes as the limit, up to the max
+	want := []int{2, 2, 3, 3, 3, 4, 4, 4, 4, 4}
+	for i, w := range want {
+		l.acquire()
+		if limit := l.release(false); limit != w {
+			t.Fatalf("success %d: got limit %d, want %d", i+1, limit, w)
+		}
+	}
+}
+
+func TestAdaptiveConcurrencyRecoversFromSlowDown(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	// the first calls are throttled
+	var mu sync.Mutex
+	puts := 0
+	fake.hook = func(ctx context.Context, op, key string) error {
+		if op != "PutObject" {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		puts++
+		if puts <= 2 {
+			return responseError(http.StatusServiceUnavailable, nil, &smithy.GenericAPIError{Code: "SlowDown"})
+		}
+		return nil
+	}
+
+	fc := testConfig("site")
+	fc.AdaptiveConcurrency = true
+	fc.MaxParallel = intPtr(4)
+	target := testTarget(t, fc, map[string]string{"a": "a", "b": "b", "c": "c", "d": "d", "e": "e", "f": "f"})
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	if stored := fake.stored(); len(stored) != 6 {
+		t.Errorf("stored %v, want all 6 files", stored)
+	}
+	if !logged(target, "throttled, retrying with at most 2 concurrent uploads") {
+		t.Errorf("no log of the reduced concurrency in %q", target.Logs)
+	}
+}
+
+func TestIsSlowDown(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{&smithy.GenericAPIError{Code: "SlowDown"}, true},
+		{responseError(http.StatusServiceUnavailable, nil, &smithy.GenericAPIError{Code: "ServiceUnavailable"}), true},
+		{accessDeniedError(), false},
+		{nil, false},
+	} {
+		if got := isSlowDown(tc.err); got != tc.want {
+			t.Errorf("isSlowDown(%v) = %t, want %t", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package s3
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/zen-io/zen-target-s3"
+
+// tracer returns the tracer of the globally configured provider when tracing is enabled, which itself is a no-op
+// until the process configures one
+func (fc S3FileConfig) tracer() trace.Tracer {
+	if !fc.Tracing {
+		return trace.NewNoopTracerProvider().Tracer(tracerName)
+	}
+
+	return otel.Tracer(tracerName)
+}
+
+// startUploadSpan starts the span of a single upload, a child of the deploy span in ctx
+func (d *deployment) startUploadSpan(ctx context.Context, job uploadJob) (context.Context, trace.Span) {
+	return d.tracer.Start(ctx, "s3.upload", trace.WithAttributes(
+		attribute.String("s3.bucket", d.bucket),
+		attribute.String("s3.key", job.key),
+		attribute.Int64("s3.size", job.size),
+	))
+}
+
+// endSpan records the outcome of an operation and ends its span
+func endSpan(span trace.Span, status string, err error) {
+	span.SetAttributes(attribute.String("s3.status", status))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,208 @@
+package s3
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordingTracer is a test exporter, keeping every span started through it
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	trace.Span // the noop span, for the methods not recorded
+
+	tracer *recordingTracer
+	name   string
+	parent *recordedSpan
+	attrs  map[attribute.Key]attribute.Value
+	status codes.Code
+	ended  bool
+}
+
+func (r *recordingTracer) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return r
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordedSpan{
+		Span:   trace.SpanFromContext(context.Background()),
+		tracer: r,
+		name:   name,
+		attrs:  map[attribute.Key]attribute.Value{},
+	}
+	span.parent, _ = trace.SpanFromContext(ctx).(*recordedSpan)
+	config := trace.NewSpanStartConfig(opts...)
+	span.SetAttributes(config.Attributes()...)
+
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func (r *recordingTracer) named(name string) []*recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	spans := []*recordedSpan{}
+	for _, span := range r.spans {
+		if span.name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	for _, attr := range kv {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, _ string) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.status = code
+}
+
+func (s *recordedSpan) End(...trace.SpanEndOption) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.ended = true
+}
+
+func (s *recordedSpan) IsRecording() bool {
+	return true
+}
+
+func useTracer(t *testing.T) *recordingTracer {
+	recorder := &recordingTracer{}
+	otel.SetTracerProvider(recorder)
+	t.Cleanup(func() { otel.SetTracerProvider(trace.NewNoopTracerProvider()) })
+	return recorder
+}
+
+func TestTracingRecordsDeployAndUploadSpans(t *testing.T) {
+	recorder := useTracer(t)
+
+	fc := testConfig("site")
+	fc.Tracing = true
+	deployFiles(t, fc, map[string]string{"index.html": "<html></html>", "app.js": "app"})
+
+	deploys := recorder.named("s3.deploy")
+	if len(deploys) != 1 {
+		t.Fatalf("recorded %d deploy spans, want 1", len(deploys))
+	}
+	if !deploys[0].ended {
+		t.Error("the deploy span was not ended")
+	}
+
+	uploads := recorder.named("s3.upload")
+	if len(uploads) != 2 {
+		t.Fatalf("recorded %d upload spans, want 2", len(uploads))
+	}
+	keys := map[string]bool{}
+	for _, span := range uploads {
+		keys[span.attrs["s3.key"].AsString()] = true
+		if span.parent != deploys[0] {
+			t.Errorf("upload span of %s is not a child of the deploy span", span.attrs["s3.key"].AsString())
+		}
+		if bucket := span.attrs["s3.bucket"].AsString(); bucket != testBucket {
+			t.Errorf("bucket attribute %q, want %q", bucket, testBucket)
+		}
+		if status := span.attrs["s3.status"].AsString(); status != summaryUploaded {
+			t.Errorf("status attribute %q, want %q", status, summaryUploaded)
+		}
+		if !span.ended {
+			t.Errorf("upload span of %s was not ended", span.attrs["s3.key"].AsString())
+		}
+	}
+	if !keys["site/index.html"] || !keys["site/app.js"] {
+		t.Errorf("upload spans for %v", keys)
+	}
+	if size := uploads[0].attrs["s3.size"].AsInt64(); size == 0 {
+		t.Error("no size attribute")
+	}
+}
+
+func TestTracingRecordsFailedUploads(t *testing.T) {
+	recorder := useTracer(t)
+	fake := newFakeS3()
+	useFake(t, fake)
+	fake.fail("PutObject", "site/index.html", accessDeniedError())
+
+	fc := testConfig("site")
+	fc.Tracing = true
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+	if err := runScript(t, fc, "deploy", target, nil); err == nil {
+		t.Fatal("deploy succeeded, want the upload to fail")
+	}
+
+	uploads := recorder.named("s3.upload")
+	if len(uploads) != 1 {
+		t.Fatalf("recorded %d upload spans, want 1", len(uploads))
+	}
+	if uploads[0].status != codes.Error || uploads[0].attrs["s3.status"].AsString() != summaryFailed {
+		t.Errorf("failed upload recorded with status %v and %q", uploads[0].status, uploads[0].attrs["s3.status"].AsString())
+	}
+	if deploy := recorder.named("s3.deploy"); len(deploy) != 1 || deploy[0].status != codes.Error {
+		t.Error("the deploy span does not record the failure")
+	}
+}
+
+func TestTracingDisabledRecordsNothing(t *testing.T) {
+	recorder := useTracer(t)
+
+	deployFiles(t, testConfig("site"), map[string]string{"index.html": "<html></html>"})
+
+	if n := len(recorder.named("s3.deploy")) + len(recorder.named("s3.upload")); n != 0 {
+		t.Errorf("recorded %d spans without tracing", n)
+	}
+}
+
+func TestBucketChecksRunUnderTheDeploySpan(t *testing.T) {
+	recorder := useTracer(t)
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	var mu sync.Mutex
+	parents := map[string]trace.Span{}
+	fake.hook = func(ctx context.Context, op, key string) error {
+		if op == "GetBucketOwnershipControls" || op == "GetBucketAcl" {
+			mu.Lock()
+			parents[op] = trace.SpanFromContext(ctx)
+			mu.Unlock()
+		}
+		return nil
+	}
+
+	fc := testConfig("site")
+	fc.Tracing = true
+	fc.InheritBucketACL = true
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	deploys := recorder.named("s3.deploy")
+	if len(deploys) != 1 {
+		t.Fatalf("recorded %d deploy spans, want 1", len(deploys))
+	}
+	for _, op := range []string{"GetBucketOwnershipControls", "GetBucketAcl"} {
+		if parents[op] != deploys[0] {
+			t.Errorf("%s was called outside of the deploy span", op)
+		}
+	}
+}
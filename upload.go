@@ -0,0 +1,96 @@
+package s3
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"golang.org/x/exp/slices"
+)
+
+// putObjectInput builds the upload request for the file at rel (relative to the target cwd), applying the object settings of the target
+func (d *deployment) putObjectInput(key, rel string, body io.Reader) (*s3.PutObjectInput, error) {
+	fc := d.fc
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(d.bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	}
+
+	if contentType := fc.contentType(rel); contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	if fc.ACL != "" {
+		input.ACL = types.ObjectCannedACL(fc.ACL)
+	} else if !d.grants.empty() {
+		d.grants.apply(input)
+	}
+	if fc.SSE != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(fc.SSE)
+	}
+	if fc.SSEKMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(fc.SSEKMSKeyID)
+	}
+	if fc.BucketKeyEnabled != nil {
+		input.BucketKeyEnabled = *fc.BucketKeyEnabled
+	}
+
+	disposition := fc.ContentDisposition
+	if val, ok := matchGlobValue(fc.ContentDispositions, rel); ok {
+		disposition = val
+	}
+	if disposition != "" {
+		interpolated, err := interpolateAtRuntime(d.target, d.runCtx, disposition, map[string]string{"BASENAME": path.Base(rel)})
+		if err != nil {
+			return nil, fmt.Errorf("interpolating content disposition for %q: %w", rel, err)
+		}
+		input.ContentDisposition = aws.String(interpolated)
+	}
+
+	encoding := fc.ContentEncoding
+	if val, ok := matchGlobValue(fc.ContentEncodings, rel); ok {
+		encoding = val
+	}
+	if encoding != "" {
+		input.ContentEncoding = aws.String(encoding)
+	}
+
+	if class := fc.storageClass(rel); class != "" {
+		input.StorageClass = types.StorageClass(class)
+	}
+
+	if location, ok := matchGlobValue(fc.Redirects, rel); ok {
+		input.WebsiteRedirectLocation = aws.String(location)
+	}
+
+	if fc.ObjectLockMode != "" {
+		input.ObjectLockMode = types.ObjectLockMode(fc.ObjectLockMode)
+		input.ObjectLockRetainUntilDate = aws.Time(d.retainUntil)
+		// S3 rejects object lock uploads without an integrity checksum
+		input.ChecksumAlgorithm = types.ChecksumAlgorithmCrc32
+	}
+
+	fc.applyRules(input, rel)
+
+	if d.aclsDisabled {
+		input.ACL = ""
+		input.GrantRead, input.GrantReadACP, input.GrantWriteACP, input.GrantFullControl = nil, nil, nil, nil
+	}
+
+	return input, nil
+}
+
+// knownContentEncodings are the values accepted for the Content-Encoding of objects
+var knownContentEncodings = []string{"gzip", "compress", "deflate", "br", "zstd", "identity"}
+
+func validateContentEncoding(encoding string) error {
+	if encoding != "" && !slices.Contains(knownContentEncodings, encoding) {
+		return fmt.Errorf("unknown content encoding %q, must be one of %s", encoding, strings.Join(knownContentEncodings, ", "))
+	}
+
+	return nil
+}
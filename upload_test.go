@@ -0,0 +1,107 @@
+package s3
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	zen_targets "github.com/zen-io/zen-core/target"
+)
+
+func TestBucketKeyEnabled(t *testing.T) {
+	fc := testConfig("site")
+	fc.SSE = string(types.ServerSideEncryptionAwsKms)
+	fc.BucketKeyEnabled = new(bool)
+	*fc.BucketKeyEnabled = true
+
+	fake, _ := deployFiles(t, fc, map[string]string{"a.txt": "a"})
+	if input := fake.putInput("site/a.txt"); !input.BucketKeyEnabled {
+		t.Error("the upload does not enable the bucket key")
+	}
+
+	fc.SSE = string(types.ServerSideEncryptionAes256)
+	if err := fc.validate(); err == nil || !strings.Contains(err.Error(), "bucket_key_enabled") {
+		t.Errorf("got %v, want bucket_key_enabled to require kms", err)
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	fc := testConfig("site")
+	fc.ContentDisposition = "inline"
+	fc.ContentDispositions = map[string]string{"reports/*.pdf": `attachment; filename="{BASENAME}"`}
+
+	fake, _ := deployFiles(t, fc, map[string]string{"reports/q1.pdf": "%PDF", "index.html": "<html></html>"})
+	if got := aws.ToString(fake.putInput("site/reports/q1.pdf").ContentDisposition); got != `attachment; filename="q1.pdf"` {
+		t.Errorf("matching file got content disposition %q", got)
+	}
+	if got := aws.ToString(fake.putInput("site/index.html").ContentDisposition); got != "inline" {
+		t.Errorf("other file got content disposition %q, want the default", got)
+	}
+}
+
+func TestContentDispositionUsesRuntimeVariables(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.ContentDisposition = `attachment; filename="{RELEASE}-{BASENAME}"`
+	target := testTarget(t, fc, map[string]string{"reports/q1.pdf": "%PDF"})
+	runCtx := &zen_targets.RuntimeContext{Variables: map[string]string{"RELEASE": "v2", "BASENAME": "shadowed"}}
+
+	if err := runScript(t, fc, "deploy", target, runCtx); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if got := aws.ToString(fake.putInput("site/reports/q1.pdf").ContentDisposition); got != `attachment; filename="v2-q1.pdf"` {
+		t.Errorf("got content disposition %q", got)
+	}
+}
+
+func TestContentEncodingAppliedToAllFiles(t *testing.T) {
+	fc := testConfig("site")
+	fc.ContentEncoding = "br"
+	fc.ContentEncodings = map[string]string{"*.gz": "gzip"}
+
+	fake, _ := deployFiles(t, fc, map[string]string{"index.html": "x", "css/site.css": "x", "data.json.gz": "x"})
+	want := map[string]string{"site/index.html": "br", "site/css/site.css": "br", "site/data.json.gz": "gzip"}
+	for key, encoding := range want {
+		if got := aws.ToString(fake.putInput(key).ContentEncoding); got != encoding {
+			t.Errorf("%s got content encoding %q, want %q", key, got, encoding)
+		}
+	}
+
+	fc.ContentEncoding = "zip"
+	if err := fc.validate(); err == nil || !strings.Contains(err.Error(), "unknown content encoding") {
+		t.Errorf("got %v, want an unknown encoding to be rejected", err)
+	}
+}
+
+func TestObjectLockRetention(t *testing.T) {
+	until := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	fc := testConfig("site")
+	fc.ObjectLockMode = string(types.ObjectLockModeCompliance)
+	fc.ObjectLockRetainUntil = until.Format(time.RFC3339)
+	fake, _ := deployFiles(t, fc, map[string]string{"a.txt": "a"})
+
+	input := fake.putInput("site/a.txt")
+	if input.ObjectLockMode != types.ObjectLockModeCompliance {
+		t.Errorf("got object lock mode %q", input.ObjectLockMode)
+	}
+	if got := aws.ToTime(input.ObjectLockRetainUntilDate); !got.Equal(until) {
+		t.Errorf("got retain until %s, want %s", got, until)
+	}
+
+	// past dates fail the deploy before uploading anything
+	fc.ObjectLockRetainUntil = time.Now().Add(-time.Hour).Format(time.RFC3339)
+	fake = newFakeS3()
+	useFake(t, fake)
+	target := testTarget(t, fc, map[string]string{"a.txt": "a"})
+	if err := runScript(t, fc, "deploy", target, nil); err == nil || !strings.Contains(err.Error(), "not in the future") {
+		t.Errorf("got %v, want the past date to be rejected", err)
+	}
+	if n := fake.count("PutObject"); n != 0 {
+		t.Errorf("made %d uploads with a past retention date", n)
+	}
+}
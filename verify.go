@@ -0,0 +1,135 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// verifySample downloads a random sample of the uploaded objects and compares them with the local files
+func (d *deployment) verifySample(ctx context.Context, files map[string]string) error {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errs := []error{}
+	for _, key := range keys {
+		if rand.Float64() >= d.fc.VerifyDownloadSampleRate {
+			continue
+		}
+
+		if err := d.verifyDownload(ctx, key, files[key]); err != nil {
+			errs = append(errs, err)
+		} else {
+			d.debugln(key, "verified it matches %q", files[key])
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (d *deployment) verifyDownload(ctx context.Context, key, f string) error {
+	out, err := d.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(d.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("downloading %q for verification: %w", key, err)
+	}
+	defer out.Body.Close()
+
+	var remote io.Reader = out.Body
+	if d.fc.Compress {
+		gz, err := gzip.NewReader(out.Body)
+		if err != nil {
+			return fmt.Errorf("decompressing %q for verification: %w", key, err)
+		}
+		defer gz.Close()
+		remote = gz
+	}
+
+	local, err := os.Open(f)
+	if err != nil {
+		return fmt.Errorf("failed to open file %q, %v", f, err)
+	}
+	defer local.Close()
+
+	same, err := sameContent(local, remote)
+	if err != nil {
+		return fmt.Errorf("comparing %q with %q: %w", f, key, err)
+	} else if !same {
+		return fmt.Errorf("s3://%s/%s does not match the contents of %q", d.bucket, key, f)
+	}
+
+	return nil
+}
+
+// sameContent compares two readers byte by byte
+func sameContent(a, b io.Reader) (bool, error) {
+	ra, rb := bufio.NewReader(a), bufio.NewReader(b)
+	bufA, bufB := make([]byte, 32*1024), make([]byte, 32*1024)
+
+	for {
+		na, errA := io.ReadFull(ra, bufA)
+		nb, errB := io.ReadFull(rb, bufB)
+
+		if !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+
+		doneA := errA == io.EOF || errA == io.ErrUnexpectedEOF
+		doneB := errB == io.EOF || errB == io.ErrUnexpectedEOF
+		if errA != nil && !doneA {
+			return false, errA
+		} else if errB != nil && !doneB {
+			return false, errB
+		} else if doneA || doneB {
+			return doneA == doneB, nil
+		}
+	}
+}
+
+// verifyUpload checks the object that was just uploaded landed with the expected size, and the expected ETag
+// when it can be computed locally with the part size the upload used. Objects encrypted with kms get an ETag that
+// is not derived from their content.
+func (d *deployment) verifyUpload(ctx context.Context, key string, body io.ReadSeeker, size, partSize int64) error {
+	head, err := d.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(d.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("verifying s3://%s/%s: %w", d.bucket, key, err)
+	}
+
+	if head.ContentLength != size {
+		return fmt.Errorf("verifying s3://%s/%s: remote size %d does not match the local %d", d.bucket, key, head.ContentLength, size)
+	}
+
+	if d.fc.SSE == string(types.ServerSideEncryptionAwsKms) {
+		return nil
+	}
+
+	etag, err := localETag(body, size, partSize)
+	if err != nil {
+		return fmt.Errorf("verifying s3://%s/%s: computing etag: %w", d.bucket, key, err)
+	}
+	if remote := strings.Trim(aws.ToString(head.ETag), `"`); remote != etag {
+		return fmt.Errorf("verifying s3://%s/%s: remote etag %s does not match the local %s", d.bucket, key, remote, etag)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestVerifyUsesTheGrownPartSize(t *testing.T) {
+	fake := newFakeS3()
+	fc := testConfig("site")
+	fc.ResumeUploads = true
+	fc.VerifyUploads = true
+	target := testTarget(t, fc, nil)
+
+	// 1 byte parts would take more than the maximum number of parts, so they grow to 3 bytes
+	content := []byte(strings.Repeat("0123456789", 2000))
+	size := int64(len(content))
+	d := &deployment{fc: fc, target: target, client: fake, partSize: 1, bucket: testBucket}
+	partSize := uploadPartSize(size, d.partSize)
+	if partSize != 3 {
+		t.Fatalf("got part size %d, want 3", partSize)
+	}
+
+	fake.startUpload("site/big.bin", content[:3])
+	body := bytes.NewReader(content)
+	input := &s3.PutObjectInput{Bucket: aws.String(testBucket), Key: aws.String("site/big.bin"), Body: body}
+	out, err := d.resumeMultipartUpload(context.Background(), input, body, size, partSize)
+	if err != nil || out == nil {
+		t.Fatalf("resume: %v, %v", out, err)
+	}
+
+	if err := d.verifyUpload(context.Background(), "site/big.bin", body, size, partSize); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+	if err := d.verifyUpload(context.Background(), "site/big.bin", body, size, d.partSize); err == nil {
+		t.Errorf("verify with the configured part size passed, the etags should differ")
+	}
+}
+
+func TestVerifySampleCatchesMismatches(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	fake.hook = func(ctx context.Context, op, key string) error {
+		// the object changes between the upload and its verification
+		if op == "GetObject" && key == "site/b.txt" {
+			fake.put(key, "tampered")
+		}
+		return nil
+	}
+
+	fc := testConfig("site")
+	fc.VerifyDownloadSampleRate = 1
+	target := testTarget(t, fc, map[string]string{"a.txt": "a", "b.txt": "b"})
+
+	err := runScript(t, fc, "deploy", target, nil)
+	if err == nil || !strings.Contains(err.Error(), "site/b.txt does not match") {
+		t.Fatalf("got error %v, want the mismatch of site/b.txt", err)
+	}
+	if strings.Contains(err.Error(), "site/a.txt") {
+		t.Errorf("got error %v, site/a.txt matches", err)
+	}
+	if n := fake.count("GetObject"); n != 2 {
+		t.Errorf("downloaded %d objects, want all of them", n)
+	}
+}
+
+func TestVerifyUploads(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.VerifyUploads = true
+	target := testTarget(t, fc, map[string]string{"a.txt": "a", "b.txt": "b"})
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if n := fake.count("HeadObject"); n != 2 {
+		t.Errorf("verified %d objects, want 2", n)
+	}
+}
+
+func TestVerifyUploadsCatchesSizeMismatches(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	fake.hook = func(ctx context.Context, op, key string) error {
+		// the object is truncated between the upload and its verification
+		if op == "HeadObject" && key == "site/b.txt" {
+			fake.put(key, "")
+		}
+		return nil
+	}
+
+	fc := testConfig("site")
+	fc.VerifyUploads = true
+	target := testTarget(t, fc, map[string]string{"a.txt": "a", "b.txt": "b"})
+
+	err := runScript(t, fc, "deploy", target, nil)
+	if err == nil || !strings.Contains(err.Error(), "s3://bucket/site/b.txt: remote size 0 does not match the local 1") {
+		t.Fatalf("got error %v, want the size mismatch of site/b.txt", err)
+	}
+}
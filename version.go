@@ -0,0 +1,38 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// versionPointerKey is the key of the version pointer, under the prefix of the destination
+func (fc S3FileConfig) versionPointerKey(prefix string) string {
+	return applyKeyCase(fc.KeyCase, path.Join(prefix, fc.VersionPointer))
+}
+
+// writeVersionPointer points the pointer object to the version that was just deployed.
+// It is a single small PUT, so readers switch from the previous version to the new one at once.
+func (d *deployment) writeVersionPointer(ctx context.Context) error {
+	if d.runCtx.DryRun {
+		d.target.Infoln("%s s3://%s/%s (-> %s)", planUpdate, d.bucket, d.pointerKey, d.version)
+		return nil
+	}
+
+	if _, err := d.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:       aws.String(d.bucket),
+		Key:          aws.String(d.pointerKey),
+		Body:         bytes.NewReader([]byte(d.version)),
+		ContentType:  aws.String("text/plain; charset=utf-8"),
+		CacheControl: aws.String("no-cache"),
+	}); err != nil {
+		return fmt.Errorf("updating version pointer %s to %s: %w", d.pointerKey, d.version, err)
+	}
+
+	d.target.Debugln("pointed s3://%s/%s to %s", d.bucket, d.pointerKey, d.version)
+	return nil
+}
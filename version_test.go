@@ -0,0 +1,100 @@
+package s3
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestVersionedDeployIsRemovedThroughTheManifest(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.RecordManifest = true
+	fc.VersionPointer = "current"
+	fc.Version = "v1"
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	want := []string{"site/" + manifestName, "site/current", "site/v1/index.html"}
+	if got := fake.stored(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("stored %v, want %v", got, want)
+	}
+	if got := string(fake.object("site/current").body); got != "v1" {
+		t.Errorf("pointer holds %q", got)
+	}
+
+	// other objects under the prefix are not the target's to remove
+	fake.put("site/unrelated.txt", "keep")
+
+	if err := runScript(t, fc, "remove", target, nil); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if got := fake.stored(); !reflect.DeepEqual(got, []string{"site/unrelated.txt"}) {
+		t.Errorf("left %v after remove, want only the unrelated object", got)
+	}
+}
+
+func TestVersionPointerIsWrittenLast(t *testing.T) {
+	fc := testConfig("site")
+	fc.VersionPointer = "current"
+	fc.Version = "v2"
+	fake, _ := deployFiles(t, fc, map[string]string{"index.html": "<html></html>", "app.js": "run()", "style.css": "body {}"})
+
+	puts := fake.inputs("PutObject")
+	if len(puts) != 4 {
+		t.Fatalf("made %d uploads, want the 3 files and the pointer", len(puts))
+	}
+	if key := aws.ToString(puts[len(puts)-1].(*s3.PutObjectInput).Key); key != "site/current" {
+		t.Errorf("uploaded %s last, want the pointer", key)
+	}
+
+	// a failed upload leaves the pointer on the previous version
+	fake = newFakeS3()
+	useFake(t, fake)
+	fake.put("site/current", "v1")
+	fake.fail("PutObject", "site/v2/app.js", accessDeniedError())
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>", "app.js": "run()"})
+	if err := runScript(t, fc, "deploy", target, nil); err == nil {
+		t.Fatal("deploy succeeded despite the failed upload")
+	}
+	if got := string(fake.object("site/current").body); got != "v1" {
+		t.Errorf("pointer moved to %q despite the failed upload", got)
+	}
+}
+
+func TestVersionedDeployWithoutPrefixIsRemoved(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+	fixedClock(t, time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC))
+
+	// without a prefix and a manifest, the keys are derived from the outs
+	fc := testConfig("")
+	fc.VersionPointer = "current"
+	fc.Version = "v1"
+	fc.DatePrefixLayout = "2006-01-02"
+	fc.Aliases = []string{"latest"}
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	want := []string{"2024-03-09/v1/index.html", "current", "latest/index.html"}
+	if got := fake.stored(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("stored %v, want %v", got, want)
+	}
+
+	if err := runScript(t, fc, "remove", target, nil); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if got := fake.stored(); len(got) != 0 {
+		t.Errorf("left %v after remove", got)
+	}
+}
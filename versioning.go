@@ -0,0 +1,37 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// checkVersioning makes sure the bucket keeps versions, without which there are none to capture
+func (d *deployment) checkVersioning(ctx context.Context) error {
+	out, err := d.client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{
+		Bucket: aws.String(d.bucket),
+	})
+	if err != nil {
+		return fmt.Errorf("reading versioning of bucket %s: %w", d.bucket, err)
+	}
+
+	if out.Status != types.BucketVersioningStatusEnabled {
+		return fmt.Errorf("capture_versions requires versioning to be enabled on bucket %s", d.bucket)
+	}
+
+	return nil
+}
+
+// recordVersion keeps the version id an object was uploaded as
+func (d *deployment) recordVersion(key, versionID string) {
+	if versionID == "" {
+		return
+	}
+
+	d.versionsMu.Lock()
+	defer d.versionsMu.Unlock()
+	d.versions[key] = versionID
+}
@@ -0,0 +1,57 @@
+package s3
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestCaptureVersionsRequiresManifest(t *testing.T) {
+	fc := testConfig("site")
+	fc.CaptureVersions = true
+	if err := fc.validate(); err == nil || !strings.Contains(err.Error(), "record_manifest") {
+		t.Errorf("got error %v, want record_manifest required", err)
+	}
+}
+
+func TestCaptureVersionsRecordsThemInTheManifest(t *testing.T) {
+	fake := newFakeS3()
+	fake.versioning = types.BucketVersioningStatusEnabled
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.RecordManifest = true
+	fc.CaptureVersions = true
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+
+	if err := runScript(t, fc, "deploy", target, nil); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	manifest, err := readManifest(context.Background(), fake, testBucket, "site/"+manifestName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := manifest.Versions["site/index.html"], fake.object("site/index.html").versionID; got == "" || got != want {
+		t.Errorf("recorded version %q, want %q", got, want)
+	}
+}
+
+func TestCaptureVersionsRequiresVersioning(t *testing.T) {
+	fake := newFakeS3()
+	useFake(t, fake)
+
+	fc := testConfig("site")
+	fc.RecordManifest = true
+	fc.CaptureVersions = true
+	target := testTarget(t, fc, map[string]string{"index.html": "<html></html>"})
+
+	if err := runScript(t, fc, "deploy", target, nil); err == nil || !strings.Contains(err.Error(), "versioning") {
+		t.Errorf("got error %v, want versioning required", err)
+	}
+	if n := fake.count("PutObject"); n != 0 {
+		t.Errorf("uploaded %d objects", n)
+	}
+}
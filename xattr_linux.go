@@ -0,0 +1,6 @@
+package s3
+
+import "golang.org/x/sys/unix"
+
+// errNoXattr is returned when reading an extended attribute that is not set
+const errNoXattr = unix.ENODATA
@@ -0,0 +1,77 @@
+//go:build linux || darwin
+
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// listXattrs returns the names of the extended attributes of path
+func listXattrs(path string) ([]string, error) {
+	size, err := unix.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+	if size, err = unix.Listxattr(path, buf); err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+
+	return names, nil
+}
+
+// getXattr returns the value of the extended attribute name of path, and false when it is not set
+func getXattr(path, name string) (string, bool, error) {
+	size, err := unix.Getxattr(path, name, nil)
+	if errors.Is(err, errNoXattr) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+
+	buf := make([]byte, size)
+	if size, err = unix.Getxattr(path, name, buf); err != nil {
+		return "", false, err
+	}
+
+	return string(buf[:size]), true, nil
+}
+
+// readFileXattrs reads the extended attributes of path. Filesystems without support for them have none.
+func readFileXattrs(path string, names []string) (map[string]string, error) {
+	if len(names) == 0 {
+		all, err := listXattrs(path)
+		if errors.Is(err, unix.ENOTSUP) {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("listing xattrs of %q: %w", path, err)
+		}
+		names = all
+	}
+
+	xattrs := map[string]string{}
+	for _, name := range names {
+		value, ok, err := getXattr(path, name)
+		if errors.Is(err, unix.ENOTSUP) {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("reading xattr %s of %q: %w", name, path, err)
+		} else if ok {
+			xattrs[name] = value
+		}
+	}
+
+	return xattrs, nil
+}